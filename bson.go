@@ -0,0 +1,505 @@
+package orderedmap
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BSON element types used by MarshalBSON and UnmarshalBSON.
+const (
+	bsonDouble    = 0x01
+	bsonString    = 0x02
+	bsonDocument  = 0x03
+	bsonArray     = 0x04
+	bsonBinary    = 0x05
+	bsonObjectID  = 0x07
+	bsonBoolean   = 0x08
+	bsonDateTime  = 0x09
+	bsonNull      = 0x0A
+	bsonInt32     = 0x10
+	bsonTimestamp = 0x11
+	bsonInt64     = 0x12
+)
+
+// BSONObjectID is a BSON ObjectId, as decoded by UnmarshalBSON.
+type BSONObjectID [12]byte
+
+// BSONTimestamp is a BSON timestamp, T holding the seconds since the epoch and
+// I an ordinal within the second.
+type BSONTimestamp struct {
+	T, I uint32
+}
+
+// BSONBinary is BSON binary data of a subtype other than generic, which is
+// decoded as []byte instead.
+type BSONBinary struct {
+	Subtype byte
+	Data    []byte
+}
+
+// bsonAppender is implemented by every OrderedMap instantiation so nested
+// ordered maps are encoded as nested documents.
+type bsonAppender interface {
+	appendBSON(dst []byte) ([]byte, error)
+}
+
+// MarshalBSON Encode the map as a BSON document with its keys in order,
+// implementing bson.Marshaler of go.mongodb.org/mongo-driver.
+// Supported values are nil, booleans, strings, integers, floats, json.Number,
+// time.Time, []byte, BSONObjectID, BSONTimestamp, BSONBinary, ordered maps,
+// maps with string keys (in sorted key order), slices, arrays and values
+// implementing MarshalBSON. Go ints are encoded as int32 when they fit, like
+// the driver does.
+func (o OrderedMap[T]) MarshalBSON() ([]byte, error) {
+	return o.appendBSON(nil)
+}
+
+func (o OrderedMap[T]) appendBSON(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, 0, 0, 0, 0) // length, set below
+	for _, k := range o.keys {
+		var err error
+		if dst, err = appendBSONElement(dst, k, o.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	return closeBSONDocument(dst, start), nil
+}
+
+// closeBSONDocument terminates the document starting at start and sets its
+// length.
+func closeBSONDocument(dst []byte, start int) []byte {
+	dst = append(dst, 0)
+	binary.LittleEndian.PutUint32(dst[start:], uint32(len(dst)-start))
+	return dst
+}
+
+func appendBSONElement(dst []byte, key string, v interface{}) ([]byte, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return nil, fmt.Errorf("orderedmap: BSON key %q contains a NUL byte", key)
+	}
+	typeAt := len(dst)
+	dst = append(dst, 0)
+	dst = append(dst, key...)
+	dst = append(dst, 0)
+	t, dst, err := appendBSONValue(dst, v)
+	if err != nil {
+		return nil, fmt.Errorf("orderedmap: BSON key %q: %w", key, err)
+	}
+	dst[typeAt] = t
+	return dst, nil
+}
+
+// appendBSONValue appends the encoding of v and returns its element type.
+func appendBSONValue(dst []byte, v interface{}) (byte, []byte, error) {
+	switch value := v.(type) {
+	case nil:
+		return bsonNull, dst, nil
+	case bsonAppender:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return bsonNull, dst, nil
+		}
+		dst, err := value.appendBSON(dst)
+		return bsonDocument, dst, err
+	case interface{ MarshalBSON() ([]byte, error) }:
+		b, err := value.MarshalBSON()
+		return bsonDocument, append(dst, b...), err
+	case bool:
+		if value {
+			return bsonBoolean, append(dst, 1), nil
+		}
+		return bsonBoolean, append(dst, 0), nil
+	case string:
+		return bsonString, appendBSONString(dst, value), nil
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return bsonInt64, binary.LittleEndian.AppendUint64(dst, uint64(i)), nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return 0, nil, err
+		}
+		return bsonDouble, binary.LittleEndian.AppendUint64(dst, math.Float64bits(f)), nil
+	case time.Time:
+		return bsonDateTime, binary.LittleEndian.AppendUint64(dst, uint64(value.UnixMilli())), nil
+	case []byte:
+		return appendBSONValue(dst, BSONBinary{Data: value})
+	case BSONBinary:
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(len(value.Data)))
+		dst = append(dst, value.Subtype)
+		return bsonBinary, append(dst, value.Data...), nil
+	case BSONObjectID:
+		return bsonObjectID, append(dst, value[:]...), nil
+	case BSONTimestamp:
+		return bsonTimestamp, binary.LittleEndian.AppendUint64(dst, uint64(value.T)<<32|uint64(value.I)), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return bsonInt32, binary.LittleEndian.AppendUint32(dst, uint32(int32(toInt64(rv)))), nil
+	case reflect.Int:
+		if i := rv.Int(); i >= math.MinInt32 && i <= math.MaxInt32 {
+			return bsonInt32, binary.LittleEndian.AppendUint32(dst, uint32(int32(i))), nil
+		}
+		return bsonInt64, binary.LittleEndian.AppendUint64(dst, uint64(rv.Int())), nil
+	case reflect.Int64, reflect.Uint32:
+		return bsonInt64, binary.LittleEndian.AppendUint64(dst, uint64(toInt64(rv))), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, nil, fmt.Errorf("%d overflows a BSON int64", rv.Uint())
+		}
+		return bsonInt64, binary.LittleEndian.AppendUint64(dst, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return bsonDouble, binary.LittleEndian.AppendUint64(dst, math.Float64bits(rv.Float())), nil
+	case reflect.String:
+		return bsonString, appendBSONString(dst, rv.String()), nil
+	case reflect.Bool:
+		return appendBSONValue(dst, rv.Bool())
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return bsonNull, dst, nil
+		}
+		return appendBSONValue(dst, rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return bsonNull, dst, nil
+		}
+		start := len(dst)
+		dst = append(dst, 0, 0, 0, 0)
+		for i := 0; i < rv.Len(); i++ {
+			var err error
+			if dst, err = appendBSONElement(dst, strconv.Itoa(i), rv.Index(i).Interface()); err != nil {
+				return 0, nil, err
+			}
+		}
+		return bsonArray, closeBSONDocument(dst, start), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if rv.IsNil() {
+			return bsonNull, dst, nil
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		start := len(dst)
+		dst = append(dst, 0, 0, 0, 0)
+		for _, k := range keys {
+			var err error
+			value := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+			if dst, err = appendBSONElement(dst, k, value.Interface()); err != nil {
+				return 0, nil, err
+			}
+		}
+		return bsonDocument, closeBSONDocument(dst, start), nil
+	}
+	return 0, nil, fmt.Errorf("cannot encode %T as BSON", v)
+}
+
+func toInt64(rv reflect.Value) int64 {
+	if rv.CanInt() {
+		return rv.Int()
+	}
+	return int64(rv.Uint())
+}
+
+func appendBSONString(dst []byte, s string) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(s)+1))
+	dst = append(dst, s...)
+	return append(dst, 0)
+}
+
+// UnmarshalBSON Decode a BSON document keeping the order of its keys,
+// implementing bson.Unmarshaler of go.mongodb.org/mongo-driver.
+// For an OrderedMap[interface{}], nested documents become
+// *OrderedMap[interface{}], arrays []interface{}, doubles float64, int32 and
+// int64 keep their size, datetimes become time.Time, generic binary data
+// []byte and other binary data BSONBinary, ObjectIDs BSONObjectID and
+// timestamps BSONTimestamp, so that they are encoded back to the same types.
+// For other value types, decoded values are converted to T when possible, and
+// documents are passed to T's UnmarshalBSON method if it has one.
+func (o *OrderedMap[T]) UnmarshalBSON(data []byte) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	r := bsonReader{data: data}
+	body, err := r.document()
+	if err != nil {
+		return err
+	}
+	if r.pos != len(data) {
+		return fmt.Errorf("orderedmap: unexpected data after BSON document")
+	}
+	o.keys = []string{}
+	o.values = map[string]T{}
+	return decodeBSONDocument(body, o)
+}
+
+// decodeBSONDocument reads the elements of a document body into o.
+func decodeBSONDocument[T any](r bsonReader, o *OrderedMap[T]) error {
+	for !r.done() {
+		t, key, err := r.element()
+		if err != nil {
+			return err
+		}
+		if _, exists := o.values[key]; !exists {
+			if err = o.validateKey(key); err != nil {
+				return err
+			}
+		}
+		var value T
+		if u, ok := bsonUnmarshaler(&value); ok && t == bsonDocument {
+			start := r.pos
+			if _, err = r.document(); err != nil {
+				return err
+			}
+			if err = u.UnmarshalBSON(r.data[start:r.pos]); err != nil {
+				return err
+			}
+		} else {
+			v, err := r.value(t, o.escapeHTML)
+			if err != nil {
+				return fmt.Errorf("orderedmap: BSON key %q: %w", key, err)
+			}
+			if err = assignValue(&value, v); err != nil {
+				return fmt.Errorf("orderedmap: BSON key %q: %w", key, err)
+			}
+		}
+		o.setLast(key, value)
+	}
+	return nil
+}
+
+// bsonUnmarshaler returns the UnmarshalBSON method of *dst, or of a newly
+// allocated value stored in *dst when T is a pointer type.
+func bsonUnmarshaler[T any](dst *T) (interface{ UnmarshalBSON([]byte) error }, bool) {
+	if u, ok := any(dst).(interface{ UnmarshalBSON([]byte) error }); ok {
+		return u, true
+	}
+	rt := reflect.TypeOf(dst).Elem()
+	if rt.Kind() != reflect.Pointer {
+		return nil, false
+	}
+	p := reflect.New(rt.Elem())
+	u, ok := p.Interface().(interface{ UnmarshalBSON([]byte) error })
+	if ok {
+		reflect.ValueOf(dst).Elem().Set(p)
+	}
+	return u, ok
+}
+
+// assignValue stores v into *dst, converting between numeric types.
+func assignValue[T any](dst *T, v interface{}) error {
+	if p, ok := any(dst).(*interface{}); ok {
+		*p = v
+		return nil
+	}
+	if v == nil {
+		var zero T
+		*dst = zero
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	target := reflect.ValueOf(dst).Elem()
+	switch {
+	case rv.Type().AssignableTo(target.Type()):
+		target.Set(rv)
+	case isNumberKind(rv.Kind()) && isNumberKind(target.Kind()):
+		target.Set(rv.Convert(target.Type()))
+	case rv.Kind() == reflect.Array && rv.Type().ConvertibleTo(target.Type()):
+		// such as a BSONObjectID into a [12]byte
+		target.Set(rv.Convert(target.Type()))
+	default:
+		return fmt.Errorf("cannot assign %T to %s", v, target.Type())
+	}
+	return nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Uintptr) || k == reflect.Float32 || k == reflect.Float64
+}
+
+// bsonReader reads a BSON document body.
+type bsonReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bsonReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *bsonReader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.data)-r.pos < n {
+		return nil, fmt.Errorf("orderedmap: truncated BSON data")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// document reads a whole document and returns a reader over its elements.
+func (r *bsonReader) document() (bsonReader, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return bsonReader{}, err
+	}
+	n := int(int32(binary.LittleEndian.Uint32(b)))
+	if n < 5 {
+		return bsonReader{}, fmt.Errorf("orderedmap: invalid BSON document length %d", n)
+	}
+	body, err := r.next(n - 4)
+	if err != nil {
+		return bsonReader{}, err
+	}
+	if body[len(body)-1] != 0 {
+		return bsonReader{}, fmt.Errorf("orderedmap: BSON document is not terminated")
+	}
+	return bsonReader{data: body[:len(body)-1]}, nil
+}
+
+func (r *bsonReader) cstring() (string, error) {
+	end := r.pos
+	for end < len(r.data) && r.data[end] != 0 {
+		end++
+	}
+	if end == len(r.data) {
+		return "", fmt.Errorf("orderedmap: truncated BSON data")
+	}
+	s := string(r.data[r.pos:end])
+	r.pos = end + 1
+	return s, nil
+}
+
+func (r *bsonReader) element() (byte, string, error) {
+	t, err := r.next(1)
+	if err != nil {
+		return 0, "", err
+	}
+	key, err := r.cstring()
+	return t[0], key, err
+}
+
+func (r *bsonReader) value(t byte, escapeHTML htmlEscaping) (interface{}, error) {
+	switch t {
+	case bsonDouble:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case bsonString:
+		b, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		n := int(int32(binary.LittleEndian.Uint32(b)))
+		if b, err = r.next(n); err != nil {
+			return nil, err
+		}
+		if n < 1 || b[n-1] != 0 {
+			return nil, fmt.Errorf("orderedmap: invalid BSON string")
+		}
+		return string(b[:n-1]), nil
+	case bsonDocument:
+		body, err := r.document()
+		if err != nil {
+			return nil, err
+		}
+		o := New[interface{}]()
+		o.escapeHTML = escapeHTML
+		if err = decodeBSONDocument(body, o); err != nil {
+			return nil, err
+		}
+		return o, nil
+	case bsonArray:
+		body, err := r.document()
+		if err != nil {
+			return nil, err
+		}
+		s := []interface{}{}
+		for !body.done() {
+			t, _, err := body.element()
+			if err != nil {
+				return nil, err
+			}
+			v, err := body.value(t, escapeHTML)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		return s, nil
+	case bsonBinary:
+		b, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		n := int(int32(binary.LittleEndian.Uint32(b)))
+		subtype, err := r.next(1)
+		if err != nil {
+			return nil, err
+		}
+		if b, err = r.next(n); err != nil {
+			return nil, err
+		}
+		data := append([]byte{}, b...)
+		if subtype[0] != 0 {
+			return BSONBinary{Subtype: subtype[0], Data: data}, nil
+		}
+		return data, nil
+	case bsonObjectID:
+		b, err := r.next(12)
+		if err != nil {
+			return nil, err
+		}
+		var id BSONObjectID
+		copy(id[:], b)
+		return id, nil
+	case bsonBoolean:
+		b, err := r.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case bsonDateTime:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMilli(int64(binary.LittleEndian.Uint64(b))), nil
+	case bsonNull:
+		return nil, nil
+	case bsonInt32:
+		b, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return int32(binary.LittleEndian.Uint32(b)), nil
+	case bsonTimestamp:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		ts := binary.LittleEndian.Uint64(b)
+		return BSONTimestamp{T: uint32(ts >> 32), I: uint32(ts)}, nil
+	case bsonInt64:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	}
+	return nil, fmt.Errorf("unsupported BSON type 0x%02x", t)
+}
@@ -0,0 +1,182 @@
+package orderedmap
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrderedMap_MarshalBSON(t *testing.T) {
+	o := New[string]()
+	o.Set("hello", "world")
+	b, err := o.MarshalBSON()
+	if err != nil {
+		t.Fatal("MarshalBSON error", err)
+	}
+	// example from bsonspec.org
+	want := []byte("\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00")
+	if !bytes.Equal(b, want) {
+		t.Errorf("MarshalBSON = %q, want %q", b, want)
+	}
+
+	bad := New[int]()
+	bad.Set("a\x00b", 1)
+	if _, err = bad.MarshalBSON(); err == nil {
+		t.Error("MarshalBSON accepted a key with a NUL byte")
+	}
+}
+
+func TestOrderedMap_BSONRoundTrip(t *testing.T) {
+	when := time.UnixMilli(1700000000123)
+	nested := New[int]()
+	nested.Set("z", 1)
+	nested.Set("a", 2)
+	o := New[interface{}]()
+	o.Set("number", 1)
+	o.Set("big", int64(1)<<40)
+	o.Set("float", 1.5)
+	o.Set("string", "s")
+	o.Set("bool", true)
+	o.Set("null", nil)
+	o.Set("time", when)
+	o.Set("bytes", []byte{1, 2})
+	o.Set("nested", nested)
+	o.Set("array", []interface{}{"x", 2})
+	o.Set("strings", []string{"y"})
+
+	b, err := o.MarshalBSON()
+	if err != nil {
+		t.Fatal("MarshalBSON error", err)
+	}
+	res := New[interface{}]()
+	if err = res.UnmarshalBSON(b); err != nil {
+		t.Fatal("UnmarshalBSON error", err)
+	}
+	if !res.SameKeyOrder(o) {
+		t.Error("UnmarshalBSON keys", res.Keys())
+	}
+	if v, _ := res.Get("number"); v != int32(1) {
+		t.Errorf("number = %#v", v)
+	}
+	if v, _ := res.Get("big"); v != int64(1)<<40 {
+		t.Errorf("big = %#v", v)
+	}
+	if v, _ := res.Get("float"); v != 1.5 {
+		t.Errorf("float = %#v", v)
+	}
+	if v, _ := res.Get("string"); v != "s" {
+		t.Errorf("string = %#v", v)
+	}
+	if v, _ := res.Get("bool"); v != true {
+		t.Errorf("bool = %#v", v)
+	}
+	if v, ok := res.Get("null"); !ok || v != nil {
+		t.Errorf("null = %#v", v)
+	}
+	if v, _ := res.Get("time"); !v.(time.Time).Equal(when) {
+		t.Errorf("time = %v", v)
+	}
+	if v, _ := res.Get("bytes"); !bytes.Equal(v.([]byte), []byte{1, 2}) {
+		t.Errorf("bytes = %#v", v)
+	}
+	v, _ := res.Get("nested")
+	n, ok := v.(*OrderedMap[interface{}])
+	if !ok {
+		t.Fatalf("nested = %#v", v)
+	}
+	if k := n.Keys(); len(k) != 2 || k[0] != "z" || k[1] != "a" {
+		t.Error("nested keys", k)
+	}
+	if v, _ := res.Get("array"); len(v.([]interface{})) != 2 || v.([]interface{})[1] != int32(2) {
+		t.Errorf("array = %#v", v)
+	}
+	if v, _ := res.Get("strings"); len(v.([]interface{})) != 1 || v.([]interface{})[0] != "y" {
+		t.Errorf("strings = %#v", v)
+	}
+}
+
+func TestOrderedMap_UnmarshalBSONTyped(t *testing.T) {
+	o := New[*OrderedMap[int]]()
+	inner := New[int]()
+	inner.Set("b", 2)
+	inner.Set("a", 1)
+	o.Set("x", inner)
+	b, err := o.MarshalBSON()
+	if err != nil {
+		t.Fatal("MarshalBSON error", err)
+	}
+	res := New[*OrderedMap[int]]()
+	if err = res.UnmarshalBSON(b); err != nil {
+		t.Fatal("UnmarshalBSON error", err)
+	}
+	x, _ := res.Get("x")
+	if k := x.Keys(); len(k) != 2 || k[0] != "b" || k[1] != "a" {
+		t.Error("typed nested keys", k)
+	}
+	if v, _ := x.Get("b"); v != 2 {
+		t.Error("typed nested value", v)
+	}
+
+	strs := New[string]()
+	if err = strs.UnmarshalBSON(b); err == nil {
+		t.Error("UnmarshalBSON assigned a document to a string")
+	}
+	if err = res.UnmarshalBSON(b[:len(b)-1]); err == nil {
+		t.Error("UnmarshalBSON accepted truncated data")
+	}
+	res.Freeze()
+	if err = res.UnmarshalBSON(b); err != ErrFrozen {
+		t.Error("UnmarshalBSON on a frozen map", err)
+	}
+}
+
+func TestOrderedMap_BSONRoundTripTypes(t *testing.T) {
+	id := BSONObjectID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	uuid := BSONBinary{Subtype: 4, Data: []byte("0123456789abcdef")}
+	ts := BSONTimestamp{T: 1700000000, I: 3}
+	for name, value := range map[string]interface{}{"objectid": id, "binary": uuid, "timestamp": ts} {
+		o := New[interface{}]()
+		o.Set("v", value)
+		b, err := o.MarshalBSON()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		res := New[interface{}]()
+		if err = res.UnmarshalBSON(b); err != nil {
+			t.Fatal(name, err)
+		}
+		v, _ := res.Get("v")
+		if !reflect.DeepEqual(v, value) {
+			t.Errorf("%s decoded as %#v", name, v)
+		}
+		again, err := res.MarshalBSON()
+		if err != nil || !bytes.Equal(again, b) {
+			t.Errorf("%s encoded back as %q, want %q", name, again, b)
+		}
+	}
+
+	// {_id: ObjectId}
+	doc := append([]byte("\x16\x00\x00\x00\x07_id\x00"), id[:]...)
+	doc = append(doc, 0)
+	res := New[interface{}]()
+	if err := res.UnmarshalBSON(doc); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := res.MarshalBSON(); err != nil || !bytes.Equal(b, doc) {
+		t.Errorf("ObjectId document encoded back as %q", b)
+	}
+}
+
+func TestOrderedMap_UnmarshalBSONObjectIDArray(t *testing.T) {
+	o := New[BSONObjectID]()
+	o.Set("_id", BSONObjectID{1, 2, 3})
+	b, _ := o.MarshalBSON()
+	res := New[[12]byte]()
+	if err := res.UnmarshalBSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.Get("_id"); v != [12]byte{1, 2, 3} {
+		t.Errorf("_id = %v", v)
+	}
+}
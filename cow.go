@@ -0,0 +1,71 @@
+package orderedmap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// COWMap is an ordered map safe for concurrent use, optimized for reads.
+// Readers load an immutable snapshot without locking, while every write
+// copies the current snapshot, modifies the copy and atomically publishes it.
+// Writes therefore allocate a full copy of the map each time, so COWMap suits
+// read-heavy workloads with rare writes; use Update to batch changes.
+type COWMap[T any] struct {
+	mu       sync.Mutex // serializes writers
+	snapshot atomic.Pointer[OrderedMap[T]]
+}
+
+func NewCOW[T any]() *COWMap[T] {
+	c := &COWMap[T]{}
+	s := New[T]()
+	s.Freeze()
+	c.snapshot.Store(s)
+	return c
+}
+
+// Snapshot Return the current content. The snapshot is frozen and never
+// changes, later writes publishing a new snapshot instead.
+func (c *COWMap[T]) Snapshot() *OrderedMap[T] {
+	return c.snapshot.Load()
+}
+
+func (c *COWMap[T]) Get(key string) (T, bool) {
+	return c.snapshot.Load().Get(key)
+}
+
+// Keys Return the keys of the current snapshot. The slice must not be
+// modified.
+func (c *COWMap[T]) Keys() []string {
+	return c.snapshot.Load().Keys()
+}
+
+func (c *COWMap[T]) Len() int {
+	return len(c.snapshot.Load().keys)
+}
+
+func (c *COWMap[T]) Set(key string, value T) {
+	c.Update(func(o *OrderedMap[T]) {
+		o.Set(key, value)
+	})
+}
+
+func (c *COWMap[T]) Delete(key string) {
+	c.Update(func(o *OrderedMap[T]) {
+		o.Delete(key)
+	})
+}
+
+// Update Apply fn to a copy of the current snapshot and publish the result.
+// Readers see either none or all of the changes made by fn.
+func (c *COWMap[T]) Update(fn func(o *OrderedMap[T])) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := c.snapshot.Load().clone()
+	fn(next)
+	next.Freeze()
+	c.snapshot.Store(next)
+}
+
+func (c *COWMap[T]) MarshalJSON() ([]byte, error) {
+	return c.snapshot.Load().MarshalJSON()
+}
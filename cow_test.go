@@ -0,0 +1,66 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCOWMap(t *testing.T) {
+	c := NewCOW[int]()
+	c.Set("b", 2)
+	c.Set("a", 1)
+	before := c.Snapshot()
+	c.Update(func(o *OrderedMap[int]) {
+		o.Set("c", 3)
+		o.Delete("b")
+	})
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Error("Get after Update", v, ok)
+	}
+	if k := c.Keys(); len(k) != 2 || k[0] != "a" || k[1] != "c" {
+		t.Error("Keys after Update", k)
+	}
+	if k := before.Keys(); len(k) != 2 || k[0] != "b" || k[1] != "a" {
+		t.Error("Snapshot changed after Update", k)
+	}
+	if !before.IsFrozen() {
+		t.Error("Snapshot is not frozen")
+	}
+	b, err := json.Marshal(c)
+	if err != nil || string(b) != `{"a":1,"c":3}` {
+		t.Error("COWMap JSON", string(b), err)
+	}
+}
+
+func TestCOWMapConcurrent(t *testing.T) {
+	c := NewCOW[int]()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c.Set(strconv.Itoa(w*100+i), i)
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s := c.Snapshot()
+				if len(s.Keys()) != len(s.values) {
+					t.Error("Inconsistent snapshot")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if c.Len() != 400 {
+		t.Error("COWMap length", c.Len(), "!= 400")
+	}
+}
@@ -0,0 +1,120 @@
+package orderedmap
+
+import "sort"
+
+// Change describes one key in a Changeset. OldIndex and NewIndex are the
+// positions of the key in the old and new map, -1 when the key is absent.
+type Change struct {
+	Key      string
+	OldIndex int
+	NewIndex int
+}
+
+// Changeset lists the differences between two ordered maps.
+// Added and Changed follow the order of the new map, Removed the order of
+// the old map and Moved the order of the new map.
+type Changeset struct {
+	Added   []Change
+	Removed []Change
+	Changed []Change
+	Moved   []Change
+}
+
+// Empty Reports whether the changeset contains no changes.
+func (c Changeset) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0 && len(c.Moved) == 0
+}
+
+// Diff Compute the changes turning oldMap into newMap. eq decides whether the
+// values of a key present in both maps are equal.
+// A key is reported as moved when its position relative to the other common
+// keys changed; the smallest such set of keys is reported.
+func Diff[T any](oldMap, newMap *OrderedMap[T], eq func(a, b T) bool) Changeset {
+	var c Changeset
+	oldIndex := make(map[string]int, len(oldMap.keys))
+	for i, k := range oldMap.keys {
+		oldIndex[k] = i
+	}
+	newIndex := make(map[string]int, len(newMap.keys))
+	for i, k := range newMap.keys {
+		newIndex[k] = i
+	}
+
+	for i, k := range oldMap.keys {
+		if _, ok := newIndex[k]; !ok {
+			c.Removed = append(c.Removed, Change{k, i, -1})
+		}
+	}
+
+	var common []Change
+	for i, k := range newMap.keys {
+		j, ok := oldIndex[k]
+		if !ok {
+			c.Added = append(c.Added, Change{k, -1, i})
+			continue
+		}
+		common = append(common, Change{k, j, i})
+		if !eq(oldMap.values[k], newMap.values[k]) {
+			c.Changed = append(c.Changed, Change{k, j, i})
+		}
+	}
+
+	// keys outside the longest run of common keys whose old positions are
+	// increasing are the ones that moved
+	stay := stableChanges(common)
+	for i, change := range common {
+		if !stay[i] {
+			c.Moved = append(c.Moved, change)
+		}
+	}
+	return c
+}
+
+// stableChanges marks the longest subsequence of changes whose OldIndex is
+// increasing.
+func stableChanges(changes []Change) []bool {
+	// tails[l] is the index in changes ending the best subsequence of length l+1
+	tails := []int{}
+	prev := make([]int, len(changes))
+	for i, change := range changes {
+		l := sort.Search(len(tails), func(j int) bool {
+			return changes[tails[j]].OldIndex >= change.OldIndex
+		})
+		if l > 0 {
+			prev[i] = tails[l-1]
+		} else {
+			prev[i] = -1
+		}
+		if l == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[l] = i
+		}
+	}
+	stay := make([]bool, len(changes))
+	if len(tails) == 0 {
+		return stay
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		stay[i] = true
+	}
+	return stay
+}
+
+// KeyDiff Return the keys of the map missing from other, in the order of the
+// map, and the keys of other missing from the map, in the order of other.
+// Values and order changes are ignored; see Diff for those.
+func (o *OrderedMap[T]) KeyDiff(other *OrderedMap[T]) (added, removed []string) {
+	added, removed = []string{}, []string{}
+	for _, k := range o.keys {
+		if _, ok := other.values[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	for _, k := range other.keys {
+		if _, ok := o.values[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,87 @@
+package orderedmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func changeKeys(changes []Change) []string {
+	keys := []string{}
+	for _, c := range changes {
+		keys = append(keys, c.Key)
+	}
+	return keys
+}
+
+func checkChangeKeys(t *testing.T, name string, changes []Change, expected []string) {
+	t.Helper()
+	keys := changeKeys(changes)
+	if len(keys) != len(expected) {
+		t.Error(name, "count", keys, "!=", expected)
+		return
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Error(name, "order", i, keys[i], "!=", expected[i])
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldMap := New[int]()
+	oldMap.Set("a", 1)
+	oldMap.Set("b", 2)
+	oldMap.Set("c", 3)
+	oldMap.Set("d", 4)
+	oldMap.Set("e", 5)
+
+	newMap := New[int]()
+	newMap.Set("a", 1)
+	newMap.Set("d", 4)
+	newMap.Set("c", 30)
+	newMap.Set("e", 5)
+	newMap.Set("f", 6)
+
+	eq := func(a, b int) bool { return a == b }
+	c := Diff(oldMap, newMap, eq)
+	checkChangeKeys(t, "Diff added", c.Added, []string{"f"})
+	checkChangeKeys(t, "Diff removed", c.Removed, []string{"b"})
+	checkChangeKeys(t, "Diff changed", c.Changed, []string{"c"})
+	checkChangeKeys(t, "Diff moved", c.Moved, []string{"d"})
+
+	if c.Added[0].OldIndex != -1 || c.Added[0].NewIndex != 4 {
+		t.Error("Diff added positions", c.Added[0])
+	}
+	if c.Removed[0].OldIndex != 1 || c.Removed[0].NewIndex != -1 {
+		t.Error("Diff removed positions", c.Removed[0])
+	}
+	if c.Changed[0].OldIndex != 2 || c.Changed[0].NewIndex != 2 {
+		t.Error("Diff changed positions", c.Changed[0])
+	}
+	if c.Moved[0].OldIndex != 3 || c.Moved[0].NewIndex != 1 {
+		t.Error("Diff moved positions", c.Moved[0])
+	}
+
+	if !Diff(oldMap, oldMap, eq).Empty() {
+		t.Error("Diff of a map with itself is not empty")
+	}
+}
+
+func TestOrderedMap_KeyDiff(t *testing.T) {
+	o := New[int]()
+	other := New[int]()
+	for _, k := range []string{"d", "a", "b", "e"} {
+		o.Set(k, 1)
+	}
+	for _, k := range []string{"c", "b", "f", "a"} {
+		other.Set(k, 2)
+	}
+	added, removed := o.KeyDiff(other)
+	if strings.Join(added, " ") != "d e" || strings.Join(removed, " ") != "c f" {
+		t.Error("KeyDiff result is incorrect", added, removed)
+	}
+	added, removed = o.KeyDiff(o)
+	if added == nil || removed == nil || len(added) != 0 || len(removed) != 0 {
+		t.Error("KeyDiff with itself", added, removed)
+	}
+}
@@ -0,0 +1,543 @@
+package orderedmap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// jsonEncoder is implemented by every OrderedMap instantiation so nested
+// ordered maps are encoded by their parent rather than through encoding/json,
+// sharing the parent's encodeState.
+type jsonEncoder interface {
+	encodeJSON(buf *bytes.Buffer, state *encodeState) error
+}
+
+// encodeState is shared by all the maps encoded during one marshal call.
+type encodeState struct {
+	// escapeHTML is taken from the map being marshaled and applies to every
+	// nested level.
+	escapeHTML htmlEscaping
+	// visiting holds the maps being encoded, identified by their values map,
+	// with the path they were reached at.
+	visiting map[uintptr]string
+	// path holds the keys and indexes leading to the value being encoded.
+	path []pathElement
+	// encoder writes leaf values to scratch; it is reused for every value.
+	encoder *json.Encoder
+	scratch bytes.Buffer
+	// floatFmt and floatPrec format float values when floatFmt is not 0.
+	floatFmt  byte
+	floatPrec int
+	// spans, when not nil, receives the offsets of the values of the object
+	// being encoded, but not of nested objects.
+	spans map[string][2]int
+	// out receives the output of MarshalJSON, which copies it.
+	out bytes.Buffer
+}
+
+// encodeStatePool holds the states released by MarshalJSON, so repeated calls
+// reuse their buffers and encoder.
+var encodeStatePool sync.Pool
+
+// maxPooledBuffer is the size above which buffers are not kept in the pool, so
+// that one large map does not pin memory.
+const maxPooledBuffer = 1 << 20
+
+func newEncodeState(escapeHTML htmlEscaping) *encodeState {
+	if state, ok := encodeStatePool.Get().(*encodeState); ok {
+		state.escapeHTML = escapeHTML
+		state.encoder.SetEscapeHTML(!escapeHTML.skipValues)
+		return state
+	}
+	state := &encodeState{escapeHTML: escapeHTML, visiting: map[uintptr]string{}}
+	state.encoder = json.NewEncoder(&state.scratch)
+	state.encoder.SetEscapeHTML(!escapeHTML.skipValues)
+	return state
+}
+
+// release resets the state and returns it to the pool. The state must not be
+// used afterwards.
+func (state *encodeState) release() {
+	if state.out.Cap() > maxPooledBuffer || state.scratch.Cap() > maxPooledBuffer {
+		return
+	}
+	for id := range state.visiting {
+		delete(state.visiting, id)
+	}
+	state.path = state.path[:0]
+	state.floatFmt, state.floatPrec = 0, 0
+	state.spans = nil
+	state.out.Reset()
+	encodeStatePool.Put(state)
+}
+
+// encodeState returns the state to marshal the map with its own settings.
+func (o OrderedMap[T]) encodeState() *encodeState {
+	state := newEncodeState(o.escapeHTML)
+	state.floatFmt = o.floatFmt
+	state.floatPrec = o.floatPrec
+	return state
+}
+
+// pathElement is a key, or an index when index is not negative.
+type pathElement struct {
+	key   string
+	index int
+}
+
+// pathString formats the current path, such as $.a[0].b, for error messages.
+func (state *encodeState) pathString() string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, p := range state.path {
+		if p.index < 0 {
+			sb.WriteByte('.')
+			sb.WriteString(p.key)
+		} else {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(p.index))
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
+
+// MarshalJSONCanonical Marshal the map to compact JSON in insertion order,
+// never escaping HTML characters whatever SetEscapeHTML says, so that equal
+// maps always produce the same bytes.
+func (o OrderedMap[T]) MarshalJSONCanonical() ([]byte, error) {
+	state := newEncodeState(htmlEscaping{skipKeys: true, skipValues: true})
+	defer state.release()
+	if err := o.encodeJSON(&state.out, state); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), state.out.Bytes()...), nil
+}
+
+// ETag Return a strong HTTP entity tag for the map, the quoted hex prefix of
+// the SHA-256 of MarshalJSONCanonical. Maps with the same entries in the same
+// order have the same ETag.
+func (o OrderedMap[T]) ETag() (string, error) {
+	b, err := o.MarshalJSONCanonical()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf(`"%x"`, sum[:16]), nil
+}
+
+// encodeJSON writes the map to buf.
+func (o OrderedMap[T]) encodeJSON(buf *bytes.Buffer, state *encodeState) error {
+	spans := state.spans
+	state.spans = nil
+	buf.WriteByte('{')
+	if _, err := o.encodeEntries(buf, state, o.keys, nil, 0); err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	state.spans = spans
+	return nil
+}
+
+// MarshalJSONWithSpans Marshal the map like MarshalJSON and also return, for
+// each top-level key, the [start, end) byte offsets of its value in the
+// output, so that it can be replaced without marshaling the map again.
+// Entries spliced from Inline values are top-level keys too.
+func (o OrderedMap[T]) MarshalJSONWithSpans() ([]byte, map[string][2]int, error) {
+	state := o.encodeState()
+	defer state.release()
+	spans := make(map[string][2]int, len(o.keys))
+	state.spans = spans
+	state.out.WriteByte('{')
+	if _, err := o.encodeEntries(&state.out, state, o.keys, nil, 0); err != nil {
+		return nil, nil, err
+	}
+	state.out.WriteByte('}')
+	return append([]byte(nil), state.out.Bytes()...), spans, nil
+}
+
+// AppendJSONString Append the JSON encoding of the map, as MarshalJSON
+// produces it, to sb. Nothing is appended when marshaling fails.
+func (o OrderedMap[T]) AppendJSONString(sb *strings.Builder) error {
+	state := o.encodeState()
+	defer state.release()
+	if err := o.encodeJSON(&state.out, state); err != nil {
+		return err
+	}
+	sb.Write(state.out.Bytes())
+	return nil
+}
+
+// marshalEntries marshals the entries of keys, in that order, as an object
+// whose keys are renamed by name when it is not nil.
+func (o OrderedMap[T]) marshalEntries(keys []string, name func(string) string) ([]byte, error) {
+	state := o.encodeState()
+	defer state.release()
+	state.out.WriteByte('{')
+	if _, err := o.encodeEntries(&state.out, state, keys, name, 0); err != nil {
+		return nil, err
+	}
+	state.out.WriteByte('}')
+	return append([]byte(nil), state.out.Bytes()...), nil
+}
+
+// encodeEntries writes the entries of keys to buf, n entries having already
+// been written to the enclosing object, and returns the new count. Keys are
+// renamed by name when it is not nil.
+// Values wrapped in Inline are spliced in place. Reaching a map that is
+// already being encoded returns an error naming the cycle instead of
+// recursing forever.
+func (o OrderedMap[T]) encodeEntries(buf *bytes.Buffer, state *encodeState, keys []string, name func(string) string, n int) (int, error) {
+	if o.values != nil {
+		id := reflect.ValueOf(o.values).Pointer()
+		if start, ok := state.visiting[id]; ok {
+			return n, fmt.Errorf("orderedmap: cycle detected: %s refers back to %s", state.pathString(), start)
+		}
+		state.visiting[id] = state.pathString()
+		defer delete(state.visiting, id)
+	}
+
+	var used map[string]bool
+	for _, k := range keys {
+		v := interface{}(o.values[k])
+		state.path = append(state.path, pathElement{k, -1})
+		if in, ok := v.(inliner); ok {
+			if used == nil {
+				used = o.ownKeys(keys, name)
+			}
+			if err := in.reserveKeys(used); err != nil {
+				return n, err
+			}
+			var err error
+			if n, err = in.encodeInline(buf, state, n); err != nil {
+				return n, err
+			}
+		} else {
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			// add key
+			key := k
+			if name != nil {
+				key = name(k)
+			}
+			writeJSONString(buf, key, !state.escapeHTML.skipKeys)
+			buf.WriteByte(':')
+			// add value
+			start := buf.Len()
+			if err := o.encodeOwnValue(buf, k, state); err != nil {
+				return n, err
+			}
+			if state.spans != nil {
+				state.spans[key] = [2]int{start, buf.Len()}
+			}
+			n++
+		}
+		state.path = state.path[:len(state.path)-1]
+	}
+	return n, nil
+}
+
+// encodeOwnValue writes the value of key, using the value encoder of the map
+// when it handles the value. Its output is compacted like the rest.
+func (o OrderedMap[T]) encodeOwnValue(buf *bytes.Buffer, key string, state *encodeState) error {
+	v := o.values[key]
+	if o.valueEncoder != nil {
+		b, handled, err := o.valueEncoder(key, v)
+		if err != nil {
+			return err
+		}
+		if handled {
+			if err = json.Compact(buf, b); err != nil {
+				return fmt.Errorf("orderedmap: value encoder returned invalid JSON for %s: %w", state.pathString(), err)
+			}
+			return nil
+		}
+	}
+	return encodeValue(buf, v, state)
+}
+
+// ownKeys returns the names of the keys that do not hold an Inline value.
+func (o OrderedMap[T]) ownKeys(keys []string, name func(string) string) map[string]bool {
+	own := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := interface{}(o.values[k]).(inliner); !ok {
+			if name != nil {
+				k = name(k)
+			}
+			own[k] = true
+		}
+	}
+	return own
+}
+
+// MarshalJSONWithKeyFunc Marshal the map with every key renamed by fn, without
+// modifying the map. Only the keys of the map itself are renamed, not those of
+// nested maps. It returns an error when fn gives two keys the same name.
+func (o OrderedMap[T]) MarshalJSONWithKeyFunc(fn func(string) string) ([]byte, error) {
+	renamed := make(map[string]string, len(o.keys))
+	seen := make(map[string]string, len(o.keys))
+	for _, k := range o.keys {
+		name := fn(k)
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("orderedmap: keys %q and %q are both renamed to %q", other, k, name)
+		}
+		seen[name] = k
+		renamed[k] = name
+	}
+	return o.marshalEntries(o.keys, func(k string) string { return renamed[k] })
+}
+
+// MarshalJSONExcept Marshal the map without the given top-level keys, keeping
+// the order of the others. The map itself is left unchanged.
+func (o OrderedMap[T]) MarshalJSONExcept(keys ...string) ([]byte, error) {
+	skip := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		skip[k] = true
+	}
+	kept := make([]string, 0, len(o.keys))
+	for _, k := range o.keys {
+		if !skip[k] {
+			kept = append(kept, k)
+		}
+	}
+	return o.marshalEntries(kept, nil)
+}
+
+// MarshalJSONOnly Marshal only the given top-level keys, in the order of the
+// map rather than of the arguments. Keys missing from the map are skipped.
+func (o OrderedMap[T]) MarshalJSONOnly(keys ...string) ([]byte, error) {
+	want := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+	}
+	kept := make([]string, 0, len(keys))
+	for _, k := range o.keys {
+		if want[k] {
+			kept = append(kept, k)
+		}
+	}
+	return o.marshalEntries(kept, nil)
+}
+
+// MarshalJSONWithPinnedKeys Marshal the map with the given keys first, in the
+// order of the arguments, followed by the other keys in map order. Pinned
+// keys missing from the map are skipped. The map itself is left unchanged.
+func (o OrderedMap[T]) MarshalJSONWithPinnedKeys(first ...string) ([]byte, error) {
+	return o.marshalEntries(o.preferredOrder(first), nil)
+}
+
+// MarshalJSONOrdered Marshal the map with its keys sorted by less, leaving the
+// order of the map unchanged. Keys comparing equal keep their relative order.
+func (o OrderedMap[T]) MarshalJSONOrdered(less func(a, b string) bool) ([]byte, error) {
+	keys := make([]string, len(o.keys))
+	copy(keys, o.keys)
+	sort.SliceStable(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return o.marshalEntries(keys, nil)
+}
+
+// MarshalJSONDelta Marshal the entries whose key is not in baseline or whose
+// value differs from the baseline one according to eq, in order, followed by
+// the keys of baseline missing from the map with a null value. The result is
+// a JSON merge patch turning baseline into the map, ApplyMergePatch aside
+// from key order and null values. A nil baseline marshals every entry.
+func (o OrderedMap[T]) MarshalJSONDelta(baseline *OrderedMap[T], eq func(a, b T) bool) ([]byte, error) {
+	if baseline == nil {
+		baseline = &OrderedMap[T]{}
+	}
+	changed := make([]string, 0, len(o.keys))
+	for _, k := range o.keys {
+		if old, ok := baseline.values[k]; !ok || !eq(o.values[k], old) {
+			changed = append(changed, k)
+		}
+	}
+	state := o.encodeState()
+	defer state.release()
+	buf := &state.out
+	buf.WriteByte('{')
+	n, err := o.encodeEntries(buf, state, changed, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range baseline.keys {
+		if _, ok := o.values[k]; !ok {
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, k, !state.escapeHTML.skipKeys)
+			buf.WriteString(":null")
+			n++
+		}
+	}
+	buf.WriteByte('}')
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// encodeValue writes v to buf. Ordered maps, []interface{} and
+// map[string]interface{} are walked so the ordered maps they contain share
+// state; everything else is written by the state's encoder.
+func encodeValue(buf *bytes.Buffer, v interface{}, state *encodeState) error {
+	switch value := v.(type) {
+	case jsonEncoder:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return value.encodeJSON(buf, state)
+	case string:
+		// escaped like keys, the same way whatever the Go version
+		writeJSONString(buf, value, !state.escapeHTML.skipValues)
+		return nil
+	case []interface{}:
+		if value == nil {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i, e := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			state.path = append(state.path, pathElement{index: i})
+			if err := encodeValue(buf, e, state); err != nil {
+				return err
+			}
+			state.path = state.path[:len(state.path)-1]
+		}
+		buf.WriteByte(']')
+		return nil
+	case map[string]interface{}:
+		if value == nil {
+			buf.WriteString("null")
+			return nil
+		}
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, k, !state.escapeHTML.skipKeys)
+			buf.WriteByte(':')
+			state.path = append(state.path, pathElement{k, -1})
+			if err := encodeValue(buf, value[k], state); err != nil {
+				return err
+			}
+			state.path = state.path[:len(state.path)-1]
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+	if state.floatFmt != 0 {
+		switch f := v.(type) {
+		case float64:
+			return state.encodeFloat(buf, f, 64)
+		case float32:
+			return state.encodeFloat(buf, float64(f), 32)
+		}
+	}
+	state.scratch.Reset()
+	if err := state.encoder.Encode(v); err != nil {
+		return err
+	}
+	// drop the newline added by Encode
+	buf.Write(bytes.TrimSuffix(state.scratch.Bytes(), []byte{'\n'}))
+	return nil
+}
+
+// encodeFloat writes f using the state's float format.
+func (state *encodeState) encodeFloat(buf *bytes.Buffer, f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return &json.UnsupportedValueError{Value: reflect.ValueOf(f), Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+	var b [64]byte
+	buf.Write(strconv.AppendFloat(b[:0], f, state.floatFmt, state.floatPrec, bits))
+	return nil
+}
+
+const hex = "0123456789abcdef"
+
+// writeJSONString writes s to buf as a JSON string, escaping it the way
+// encoding/json does since Go 1.22, whatever the Go version, so that the
+// output does not depend on it.
+func writeJSONString(buf *bytes.Buffer, s string, escapeHTML bool) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && (!escapeHTML || (b != '<' && b != '>' && b != '&')) {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '\\', '"':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			case '\b':
+				buf.WriteString(`\b`)
+			case '\f':
+				buf.WriteString(`\f`)
+			default:
+				// control characters and, when escaping HTML, <, > and &
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[b>>4])
+				buf.WriteByte(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JavaScript
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+// Compact Validate a JSON object and return it in compact form with its keys
+// in their original order, at every level. Numbers are kept as written and
+// HTML characters are not escaped, so compacting compact output is a no-op.
+func Compact(data []byte) ([]byte, error) {
+	o := New[interface{}]()
+	o.SetUseNumber(true)
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, err
+	}
+	return o.MarshalJSONCanonical()
+}
@@ -0,0 +1,628 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSONCycle(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("a", 1)
+	child := New[interface{}]()
+	o.Set("b", []interface{}{map[string]interface{}{"c": child}})
+	child.Set("d", o)
+	_, err := json.Marshal(o)
+	if err == nil {
+		t.Fatal("Marshal did not detect the cycle")
+	}
+	if !strings.Contains(err.Error(), "$.b[0].c.d refers back to $") {
+		t.Error("Cycle error does not name the cycle", err)
+	}
+
+	self := New[interface{}]()
+	self.Set("self", self)
+	if _, err = self.MarshalJSON(); err == nil {
+		t.Error("MarshalJSON did not detect the self reference")
+	}
+}
+
+func TestMarshalJSONSharedValue(t *testing.T) {
+	shared := New[int]()
+	shared.Set("x", 1)
+	o := New[interface{}]()
+	o.Set("a", shared)
+	o.Set("b", shared)
+	var nilMap *OrderedMap[int]
+	o.Set("c", nilMap)
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal("Marshal rejected a shared value", err)
+	}
+	if string(b) != `{"a":{"x":1},"b":{"x":1},"c":null}` {
+		t.Error("JSON Marshal value is incorrect", string(b))
+	}
+}
+
+func TestMarshalJSONCanonical(t *testing.T) {
+	a := New[interface{}]()
+	a.Set("b", "<x>")
+	a.Set("a", 1)
+	nested := New[interface{}]()
+	nested.Set("c", []interface{}{1.5, "&"})
+	a.Set("n", nested)
+
+	b := New[interface{}]()
+	b.SetEscapeHTML(false)
+	if err := json.Unmarshal([]byte(`{ "b" : "<x>", "a": 1.0, "n": {"c": [1.5, "&"]} }`), &b); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+
+	ca, err := a.MarshalJSONCanonical()
+	if err != nil {
+		t.Fatal("MarshalJSONCanonical error", err)
+	}
+	cb, err := b.MarshalJSONCanonical()
+	if err != nil {
+		t.Fatal("MarshalJSONCanonical error", err)
+	}
+	expected := `{"b":"<x>","a":1,"n":{"c":[1.5,"&"]}}`
+	if string(ca) != expected {
+		t.Error("MarshalJSONCanonical value is incorrect", string(ca))
+	}
+	if string(ca) != string(cb) {
+		t.Error("MarshalJSONCanonical differs for equal maps", string(ca), string(cb))
+	}
+}
+
+func TestOrderedMap_ETag(t *testing.T) {
+	a := New[interface{}]()
+	a.Set("x", 1)
+	a.Set("y", "<b>")
+	b := New[interface{}]()
+	b.SetEscapeHTML(false)
+	b.Set("x", 1)
+	b.Set("y", "<b>")
+	ea, err := a.ETag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eb, _ := b.ETag()
+	if ea != eb || len(ea) != 34 || ea[0] != '"' || ea[33] != '"' {
+		t.Error("ETag of equal maps", ea, eb)
+	}
+	b.Sort(func(p, q *Pair[interface{}]) bool { return p.key > q.key })
+	if eb, _ = b.ETag(); ea == eb {
+		t.Error("ETag ignores the order")
+	}
+}
+
+func TestOrderedMap_SetEscapeHTMLKeysValues(t *testing.T) {
+	cases := []struct {
+		keys, values bool
+		want         string
+	}{
+		{true, true, `{"\u003ck\u003e":"\u0026v","n":{"\u003cm\u003e":["\u0026"]}}`},
+		{true, false, `{"\u003ck\u003e":"&v","n":{"\u003cm\u003e":["&"]}}`},
+		{false, true, `{"<k>":"\u0026v","n":{"<m>":["\u0026"]}}`},
+		{false, false, `{"<k>":"&v","n":{"<m>":["&"]}}`},
+	}
+	for _, c := range cases {
+		nested := New[interface{}]()
+		nested.Set("<m>", []interface{}{"&"})
+		o := New[interface{}]()
+		o.Set("<k>", "&v")
+		o.Set("n", nested)
+		o.SetEscapeHTMLKeys(c.keys)
+		o.SetEscapeHTMLValues(c.values)
+		b, err := o.MarshalJSON()
+		if err != nil {
+			t.Fatal("MarshalJSON error", err)
+		}
+		if string(b) != c.want {
+			t.Errorf("keys %v, values %v: got %s, want %s", c.keys, c.values, b, c.want)
+		}
+	}
+}
+
+func TestWriteJSONString(t *testing.T) {
+	strs := []string{
+		"",
+		"plain",
+		"quote\" backslash\\ slash/",
+		"\n\r\t\x00\x1f\x7f",
+		"<html> & </html>",
+		"unicode \u00e9 \u2713 \u2028 \u2029",
+		"invalid \xff utf8",
+	}
+	for _, escapeHTML := range []bool{true, false} {
+		for _, s := range strs {
+			var expected bytes.Buffer
+			encoder := json.NewEncoder(&expected)
+			encoder.SetEscapeHTML(escapeHTML)
+			if err := encoder.Encode(s); err != nil {
+				t.Fatal("Encode error", err)
+			}
+			var buf bytes.Buffer
+			writeJSONString(&buf, s, escapeHTML)
+			if buf.String()+"\n" != expected.String() {
+				t.Errorf("writeJSONString(%q, %v) = %s, expected %s", s, escapeHTML, buf.String(), expected.String())
+			}
+		}
+	}
+
+	// encoding/json only uses \b and \f since Go 1.22
+	var buf bytes.Buffer
+	writeJSONString(&buf, "k\b\f\x01", false)
+	if buf.String() != `"k\b\f\u0001"` {
+		t.Error("writeJSONString of \\b and \\f", buf.String())
+	}
+	o := New[string]()
+	o.Set("k\b\f", "v\b\f")
+	if b, _ := o.MarshalJSON(); string(b) != `{"k\b\f":"v\b\f"}` {
+		t.Error("keys and values escaped differently", string(b))
+	}
+}
+
+func TestMarshalJSONCompact(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("a", 1)
+	o.Set("b", []interface{}{"x", map[string]interface{}{"c": true}})
+	b, err := o.MarshalJSON()
+	if err != nil {
+		t.Fatal("MarshalJSON error", err)
+	}
+	if string(b) != `{"a":1,"b":["x",{"c":true}]}` {
+		t.Error("MarshalJSON value is not compact", string(b))
+	}
+}
+
+// marshalJSONEncoderPerValue is the former MarshalJSON implementation,
+// kept to compare against in benchmarks.
+func marshalJSONEncoderPerValue[T any](o *OrderedMap[T]) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(!o.escapeHTML.skipValues)
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encoder.Encode(k); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := encoder.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func benchmarkMap() *OrderedMap[interface{}] {
+	o := New[interface{}]()
+	for i := 0; i < 10000; i++ {
+		switch i % 3 {
+		case 0:
+			o.Set("key"+strconv.Itoa(i), i)
+		case 1:
+			o.Set("key"+strconv.Itoa(i), "value "+strconv.Itoa(i))
+		default:
+			o.Set("key"+strconv.Itoa(i), float64(i)/3)
+		}
+	}
+	return o
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	o := benchmarkMap()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := o.MarshalJSON(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalJSONEncoderPerValue(b *testing.B) {
+	o := benchmarkMap()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := marshalJSONEncoderPerValue(o); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMarshalJSONUnpooled marshals with a new state and buffer on every
+// call, as MarshalJSON did before pooling them.
+func BenchmarkMarshalJSONUnpooled(b *testing.B) {
+	o := benchmarkMap()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := o.encodeJSON(&buf, o.encodeState()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendJSONString(b *testing.B) {
+	o := benchmarkMap()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sb strings.Builder
+		if err := o.AppendJSONString(&sb); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMarshalJSONWrite is what AppendJSONString replaces.
+func BenchmarkMarshalJSONWrite(b *testing.B) {
+	o := benchmarkMap()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sb strings.Builder
+		data, err := o.MarshalJSON()
+		if err != nil {
+			b.Fatal(err)
+		}
+		sb.Write(data)
+	}
+}
+
+func TestMarshalJSONConcurrent(t *testing.T) {
+	maps := []*OrderedMap[interface{}]{benchmarkMap(), New[interface{}]()}
+	maps[1].SetEscapeHTML(false)
+	maps[1].SetFloatFormat('f', 2)
+	maps[1].Set("<b>", 1.5)
+	want := make([]string, len(maps))
+	for i, o := range maps {
+		b, err := o.MarshalJSON()
+		if err != nil {
+			t.Fatal("MarshalJSON error", err)
+		}
+		want[i] = string(b)
+	}
+	bad := New[interface{}]()
+	bad.Set("nan", math.NaN())
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				o := maps[(g+i)%2]
+				b, err := o.MarshalJSON()
+				if err != nil || string(b) != want[(g+i)%2] {
+					errs <- fmt.Sprint(string(b), err)
+					return
+				}
+				if _, err = bad.MarshalJSON(); err == nil {
+					errs <- "MarshalJSON accepted NaN"
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error("concurrent MarshalJSON output differs:", e)
+	}
+}
+
+func TestMarshalJSONWithKeyFunc(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("b", 1)
+	nested := New[interface{}]()
+	nested.Set("c", 2)
+	o.Set("a", nested)
+	b, err := o.MarshalJSONWithKeyFunc(strings.ToUpper)
+	if err != nil {
+		t.Fatal("MarshalJSONWithKeyFunc error", err)
+	}
+	if string(b) != `{"B":1,"A":{"c":2}}` {
+		t.Error("MarshalJSONWithKeyFunc value is incorrect", string(b))
+	}
+	if k := o.Keys(); k[0] != "b" || k[1] != "a" {
+		t.Error("MarshalJSONWithKeyFunc modified the keys", k)
+	}
+	o.Set("B", 3)
+	if _, err = o.MarshalJSONWithKeyFunc(strings.ToUpper); err == nil {
+		t.Error("MarshalJSONWithKeyFunc accepted duplicate keys")
+	}
+}
+
+func TestMarshalJSONExcept(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("user", "bob")
+	o.Set("password", "secret")
+	o.Set("id", 7)
+	b, err := o.MarshalJSONExcept("password", "missing")
+	if err != nil {
+		t.Fatal("MarshalJSONExcept error", err)
+	}
+	if string(b) != `{"user":"bob","id":7}` {
+		t.Error("MarshalJSONExcept value is incorrect", string(b))
+	}
+	if _, ok := o.Get("password"); !ok {
+		t.Error("MarshalJSONExcept deleted the key")
+	}
+	if b, _ = o.MarshalJSONExcept("user", "password", "id"); string(b) != `{}` {
+		t.Error("MarshalJSONExcept without keys left", string(b))
+	}
+}
+
+func TestMarshalJSONOnly(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("user", "bob")
+	o.Set("password", "secret")
+	o.Set("id", 7)
+	b, err := o.MarshalJSONOnly("id", "missing", "user")
+	if err != nil {
+		t.Fatal("MarshalJSONOnly error", err)
+	}
+	if string(b) != `{"user":"bob","id":7}` {
+		t.Error("MarshalJSONOnly value is incorrect", string(b))
+	}
+	if b, _ = o.MarshalJSONOnly(); string(b) != `{}` {
+		t.Error("MarshalJSONOnly without keys", string(b))
+	}
+}
+
+func TestMarshalJSONWithPinnedKeys(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("name", "x")
+	o.Set("type", "t")
+	o.Set("size", 1)
+	o.Set("id", 7)
+	b, err := o.MarshalJSONWithPinnedKeys("id", "missing", "type", "id")
+	if err != nil {
+		t.Fatal("MarshalJSONWithPinnedKeys error", err)
+	}
+	if string(b) != `{"id":7,"type":"t","name":"x","size":1}` {
+		t.Error("MarshalJSONWithPinnedKeys value is incorrect", string(b))
+	}
+	if k := o.Keys(); k[0] != "name" || k[3] != "id" {
+		t.Error("MarshalJSONWithPinnedKeys modified the keys", k)
+	}
+}
+
+func TestMarshalJSONOrdered(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"ccc", "a", "bb", "d"} {
+		o.Set(k, i)
+	}
+	b, err := o.MarshalJSONOrdered(func(a, b string) bool { return len(a) < len(b) })
+	if err != nil {
+		t.Fatal("MarshalJSONOrdered error", err)
+	}
+	if string(b) != `{"a":1,"d":3,"bb":2,"ccc":0}` {
+		t.Error("MarshalJSONOrdered value is incorrect", string(b))
+	}
+	if k := strings.Join(o.Keys(), " "); k != "ccc a bb d" {
+		t.Error("MarshalJSONOrdered modified the keys", k)
+	}
+}
+
+func TestMarshalJSONDelta(t *testing.T) {
+	baseline := New[int]()
+	baseline.Set("a", 1)
+	baseline.Set("b", 2)
+	baseline.Set("c", 3)
+	o := New[int]()
+	o.Set("d", 4)
+	o.Set("b", 5)
+	o.Set("a", 1)
+	eq := func(a, b int) bool { return a == b }
+	delta, err := o.MarshalJSONDelta(baseline, eq)
+	if err != nil {
+		t.Fatal("MarshalJSONDelta error", err)
+	}
+	if string(delta) != `{"d":4,"b":5,"c":null}` {
+		t.Error("MarshalJSONDelta value is incorrect", string(delta))
+	}
+	if err = baseline.ApplyMergePatch(delta); err != nil {
+		t.Fatal("ApplyMergePatch error", err)
+	}
+	for _, k := range o.Keys() {
+		if v, _ := baseline.Get(k); v != o.MustGet(k) || len(baseline.Keys()) != 3 {
+			t.Error("applying the delta did not give the map", baseline.Keys())
+		}
+	}
+	if delta, _ = o.MarshalJSONDelta(o, eq); string(delta) != `{}` {
+		t.Error("MarshalJSONDelta against itself", string(delta))
+	}
+	if delta, _ = o.MarshalJSONDelta(nil, eq); string(delta) != `{"d":4,"b":5,"a":1}` {
+		t.Error("MarshalJSONDelta against nil", string(delta))
+	}
+}
+
+func TestOrderedMap_SetValueEncoder(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	nested := New[interface{}]()
+	nested.Set("t", when)
+	o := New[interface{}]()
+	o.Set("t", when)
+	o.Set("n", 1)
+	o.Set("nested", nested)
+	o.SetValueEncoder(func(key string, value interface{}) ([]byte, bool, error) {
+		if t, ok := value.(time.Time); ok {
+			return []byte(" \"" + t.Format("2006-01-02") + "\" "), true, nil
+		}
+		return nil, false, nil
+	})
+	b, err := o.MarshalJSON()
+	if err != nil {
+		t.Fatal("MarshalJSON error", err)
+	}
+	if string(b) != `{"t":"2024-05-06","n":1,"nested":{"t":"2024-05-06T07:08:09Z"}}` {
+		t.Error("SetValueEncoder output is incorrect", string(b))
+	}
+
+	failure := errors.New("failure")
+	o.SetValueEncoder(func(key string, value interface{}) ([]byte, bool, error) {
+		if key == "n" {
+			return nil, false, failure
+		}
+		return nil, false, nil
+	})
+	if _, err = o.MarshalJSON(); err != failure {
+		t.Error("SetValueEncoder error", err)
+	}
+	o.SetValueEncoder(func(key string, value interface{}) ([]byte, bool, error) {
+		return []byte("{"), true, nil
+	})
+	if _, err = o.MarshalJSON(); err == nil {
+		t.Error("SetValueEncoder accepted invalid JSON")
+	}
+	o.SetValueEncoder(nil)
+	if _, err = o.MarshalJSON(); err != nil {
+		t.Error("MarshalJSON after removing the value encoder", err)
+	}
+}
+
+func TestMarshalJSONWithSpans(t *testing.T) {
+	nested := New[interface{}]()
+	nested.Set("x", "<&>")
+	extra := New[interface{}]()
+	extra.Set("i", []interface{}{1, "two"})
+	o := New[interface{}]()
+	o.Set("a", 1.5)
+	o.Set("n", nested)
+	o.Set("s", "quote\"d\u2028")
+	o.Set("extra", Inline[interface{}]{extra})
+	o.Set("z", nil)
+	b, spans, err := o.MarshalJSONWithSpans()
+	if err != nil {
+		t.Fatal("MarshalJSONWithSpans error", err)
+	}
+	if want, _ := o.MarshalJSON(); string(b) != string(want) {
+		t.Error("MarshalJSONWithSpans output differs from MarshalJSON", string(b))
+	}
+	want := map[string]string{
+		"a": `1.5`,
+		"n": `{"x":"\u003c\u0026\u003e"}`,
+		"s": `"quote\"d\u2028"`,
+		"i": `[1,"two"]`,
+		"z": `null`,
+	}
+	if len(spans) != len(want) {
+		t.Error("MarshalJSONWithSpans spans", spans)
+	}
+	for k, v := range want {
+		span, ok := spans[k]
+		if !ok || string(b[span[0]:span[1]]) != v {
+			t.Errorf("span of %q = %v in %s, want %s", k, span, b, v)
+		}
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("b", "<x>")
+	o.Set("a", []interface{}{1})
+	var sb strings.Builder
+	sb.WriteString("data=")
+	if err := o.AppendJSONString(&sb); err != nil {
+		t.Fatal("AppendJSONString error", err)
+	}
+	want, _ := o.MarshalJSON()
+	if sb.String() != "data="+string(want) {
+		t.Error("AppendJSONString output is incorrect", sb.String())
+	}
+	o.Set("bad", math.Inf(1))
+	if err := o.AppendJSONString(&sb); err == nil || sb.String() != "data="+string(want) {
+		t.Error("AppendJSONString on error", err, sb.String())
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := `{
+  "z": 1.50,
+  "a": [ {"y": "<b>", "x": null}, 1e3 ],
+  "m": {}
+}`
+	b, err := Compact([]byte(s))
+	if err != nil {
+		t.Fatal("Compact error", err)
+	}
+	expected := `{"z":1.50,"a":[{"y":"<b>","x":null},1e3],"m":{}}`
+	if string(b) != expected {
+		t.Error("Compact value is incorrect", string(b))
+	}
+	again, err := Compact(b)
+	if err != nil {
+		t.Fatal("Compact error", err)
+	}
+	if string(again) != expected {
+		t.Error("Compact is not idempotent", string(again))
+	}
+	for _, bad := range []string{`{"a":}`, `[1]`, `{"a":1} x`} {
+		if _, err = Compact([]byte(bad)); err == nil {
+			t.Error("Compact accepted", bad)
+		}
+	}
+}
+
+func TestOrderedMap_SetFloatFormat(t *testing.T) {
+	o := New[float64]()
+	o.Set("a", 1)
+	o.Set("b", 2.345)
+	o.Set("c", 1e21)
+	o.SetFloatFormat('f', 2)
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	if string(b) != `{"a":1.00,"b":2.35,"c":1000000000000000000000.00}` {
+		t.Error("SetFloatFormat value is incorrect", string(b))
+	}
+	o.SetFloatFormat(0, 0)
+	b, _ = json.Marshal(o)
+	if string(b) != `{"a":1,"b":2.345,"c":1e+21}` {
+		t.Error("Default float format value is incorrect", string(b))
+	}
+
+	nested := New[interface{}]()
+	nested.Set("x", float32(0.5))
+	nested.Set("y", 3)
+	doc := New[interface{}]()
+	doc.Set("n", nested)
+	doc.Set("l", []interface{}{0.25})
+	doc.SetFloatFormat('e', 1)
+	b, _ = json.Marshal(doc)
+	if string(b) != `{"n":{"x":5.0e-01,"y":3},"l":[2.5e-01]}` {
+		t.Error("SetFloatFormat nested value is incorrect", string(b))
+	}
+
+	o.SetFloatFormat('g', -1)
+	o.Set("nan", math.NaN())
+	if _, err = json.Marshal(o); err == nil {
+		t.Error("Marshal accepted NaN")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SetFloatFormat accepted an invalid format")
+		}
+	}()
+	o.SetFloatFormat('x', -1)
+}
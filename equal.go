@@ -0,0 +1,225 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+// DeepEqual Report whether a and b hold the same keys in the same order with
+// deeply equal values. Nested ordered maps are compared the same way, slices
+// element by element and numbers by value, so float64(1), int(1) and
+// json.Number("1") are equal. Integers are compared exactly, so large IDs
+// differing in their last digit are not equal. Other values are compared with
+// reflect.DeepEqual.
+func DeepEqual(a, b *OrderedMap[interface{}]) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return valuesEqual(a, b, true)
+}
+
+// valuesEqual compares values of decoded documents: nested ordered maps and
+// slices element by element and numbers by value, as numbersEqual does. When
+// strict is false, as for the JSON Patch "test" operation, the order of
+// object members is not significant and maps with string keys and slices of
+// any type match the *OrderedMap[interface{}] and []interface{} decoded from
+// the same JSON.
+func valuesEqual(a, b interface{}, strict bool) bool {
+	if !strict {
+		a, b = looseValue(a), looseValue(b)
+	}
+	switch x := a.(type) {
+	case *OrderedMap[interface{}]:
+		y, ok := b.(*OrderedMap[interface{}])
+		if !ok || (x == nil) != (y == nil) {
+			return false
+		}
+		if x == nil || x == y {
+			return true
+		}
+		if len(x.keys) != len(y.keys) {
+			return false
+		}
+		for i, k := range x.keys {
+			if strict && y.keys[i] != k {
+				return false
+			}
+			w, ok := y.values[k]
+			if !ok || !valuesEqual(x.values[k], w, strict) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !valuesEqual(x[i], y[i], strict) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !valuesEqual(v, w, strict) {
+				return false
+			}
+		}
+		return true
+	}
+	if equal, numeric := numbersEqual(a, b); numeric {
+		return equal
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// looseValue converts maps with string keys to *OrderedMap[interface{}], in
+// sorted key order, and slices and arrays other than []byte to
+// []interface{}, one level deep. Other values are returned as is.
+func looseValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, *OrderedMap[interface{}], []interface{}, []byte, string:
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || rv.IsNil() {
+			break
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		o := New[interface{}]()
+		for _, k := range keys {
+			o.Set(k, rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())).Interface())
+		}
+		return o
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			break
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = rv.Index(i).Interface()
+		}
+		return s
+	}
+	return v
+}
+
+// numbersEqual compares a and b by value when either is a number, reporting
+// numeric false otherwise. Integers, including integral floats and
+// json.Number literals, are compared exactly; float64 is only used when one
+// of the numbers is not an integer.
+func numbersEqual(a, b interface{}) (equal, numeric bool) {
+	xi, xf, xok := numberValue(a)
+	yi, yf, yok := numberValue(b)
+	if !xok && !yok {
+		return false, false
+	}
+	if !xok || !yok {
+		return false, true
+	}
+	if xi != nil && yi != nil {
+		return xi.Cmp(yi) == 0, true
+	}
+	// an integer never equals a number with a fractional part
+	return xi == nil && yi == nil && xf == yf, true
+}
+
+// numberValue converts the numeric types found in decoded documents to an
+// exact integer when they hold one, or to a float64 otherwise.
+func numberValue(v interface{}) (*big.Int, float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return floatValue(n)
+	case float32:
+		return floatValue(float64(n))
+	case json.Number:
+		if i, ok := new(big.Int).SetString(string(n), 10); ok {
+			return i, 0, true
+		}
+		if r, ok := new(big.Rat).SetString(string(n)); ok && r.IsInt() {
+			return r.Num(), 0, true
+		}
+		f, err := n.Float64()
+		return nil, f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), 0, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint()), 0, true
+	}
+	return nil, 0, false
+}
+
+func floatValue(f float64) (*big.Int, float64, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) {
+		return nil, f, true
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return i, 0, true
+}
+
+// SameKeyOrder Report whether other holds the same keys in the same order,
+// ignoring values.
+func (o *OrderedMap[T]) SameKeyOrder(other *OrderedMap[T]) bool {
+	if len(o.keys) != len(other.keys) {
+		return false
+	}
+	for i, k := range o.keys {
+		if other.keys[i] != k {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualToMap Report whether the map holds exactly the keys of order, in that
+// order, with values equal, according to eq, to those of m. m must hold
+// exactly the keys of order too.
+func (o *OrderedMap[T]) EqualToMap(m map[string]T, order []string, eq func(a, b T) bool) bool {
+	if len(o.keys) != len(order) || len(m) != len(order) {
+		return false
+	}
+	for i, k := range o.keys {
+		if order[i] != k {
+			return false
+		}
+		expected, ok := m[k]
+		if !ok || !eq(o.values[k], expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsReorderOf Report whether other holds the same keys with values equal
+// according to eq, but in a different order.
+func (o *OrderedMap[T]) IsReorderOf(other *OrderedMap[T], eq func(a, b T) bool) bool {
+	if len(o.keys) != len(other.keys) || o.SameKeyOrder(other) {
+		return false
+	}
+	for _, k := range o.keys {
+		v, ok := other.values[k]
+		if !ok || !eq(o.values[k], v) {
+			return false
+		}
+	}
+	return true
+}
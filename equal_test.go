@@ -0,0 +1,166 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeepEqual(t *testing.T) {
+	s := `{"a":1,"b":{"c":[1,"x",{"d":null}],"e":true}}`
+	a := New[interface{}]()
+	if err := json.Unmarshal([]byte(s), a); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	b := New[interface{}]()
+	b.SetUseNumber(true)
+	if err := json.Unmarshal([]byte(s), b); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if !DeepEqual(a, b) {
+		t.Error("DeepEqual of the same document")
+	}
+
+	c := New[interface{}]()
+	nested := New[interface{}]()
+	d := New[interface{}]()
+	d.Set("d", nil)
+	nested.Set("c", []interface{}{1, "x", d})
+	nested.Set("e", true)
+	c.Set("a", int64(1))
+	c.Set("b", nested)
+	if !DeepEqual(a, c) {
+		t.Error("DeepEqual of a built document")
+	}
+
+	reordered := New[interface{}]()
+	if err := json.Unmarshal([]byte(`{"a":1,"b":{"e":true,"c":[1,"x",{"d":null}]}}`), reordered); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if DeepEqual(a, reordered) {
+		t.Error("DeepEqual ignores nested order")
+	}
+	changed := New[interface{}]()
+	if err := json.Unmarshal([]byte(`{"a":1,"b":{"c":[1,"x",{"d":0}],"e":true}}`), changed); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if DeepEqual(a, changed) {
+		t.Error("DeepEqual ignores nested values")
+	}
+	if DeepEqual(a, nil) || !DeepEqual(nil, nil) {
+		t.Error("DeepEqual with nil maps")
+	}
+}
+
+func TestDeepEqualLargeIntegers(t *testing.T) {
+	a := New[interface{}]()
+	a.SetUseNumber(true)
+	b := New[interface{}]()
+	b.SetUseNumber(true)
+	if err := json.Unmarshal([]byte(`{"id":123456789012345678}`), a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"id":123456789012345679}`), b); err != nil {
+		t.Fatal(err)
+	}
+	if DeepEqual(a, b) {
+		t.Error("DeepEqual of different large json.Number IDs")
+	}
+	a = New[interface{}]()
+	a.Set("id", int64(9007199254740993))
+	b = New[interface{}]()
+	b.Set("id", int64(9007199254740992))
+	if DeepEqual(a, b) {
+		t.Error("DeepEqual of different large int64 IDs")
+	}
+	b.Set("id", json.Number("9007199254740993"))
+	if !DeepEqual(a, b) {
+		t.Error("DeepEqual of the same large ID")
+	}
+	for _, pair := range [][2]interface{}{
+		{float64(2), json.Number("2.0")},
+		{json.Number("1e3"), 1000},
+		{1.5, json.Number("1.5")},
+		{uint64(1) << 63, json.Number("9223372036854775808")},
+	} {
+		if equal, numeric := numbersEqual(pair[0], pair[1]); !equal || !numeric {
+			t.Errorf("numbersEqual(%#v, %#v) is false", pair[0], pair[1])
+		}
+	}
+	if equal, _ := numbersEqual(1.5, 1); equal {
+		t.Error("numbersEqual of an integer and a fraction")
+	}
+}
+
+func TestOrderedMap_SameKeyOrder(t *testing.T) {
+	a := New[int]()
+	a.Set("x", 1)
+	a.Set("y", 2)
+	b := New[int]()
+	b.Set("x", 10)
+	b.Set("y", 20)
+	if !a.SameKeyOrder(b) {
+		t.Error("SameKeyOrder with different values")
+	}
+	b.Set("z", 30)
+	if a.SameKeyOrder(b) {
+		t.Error("SameKeyOrder with different lengths")
+	}
+	c := New[int]()
+	c.Set("y", 2)
+	c.Set("x", 1)
+	if a.SameKeyOrder(c) {
+		t.Error("SameKeyOrder with different orders")
+	}
+}
+
+func TestOrderedMap_EqualToMap(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 1)
+	o.Set("a", 2)
+	eq := func(a, b int) bool { return a == b }
+	if !o.EqualToMap(map[string]int{"a": 2, "b": 1}, []string{"b", "a"}, eq) {
+		t.Error("EqualToMap of an equal map")
+	}
+	cases := []struct {
+		m     map[string]int
+		order []string
+	}{
+		{map[string]int{"a": 2, "b": 1}, []string{"a", "b"}},
+		{map[string]int{"a": 2, "b": 3}, []string{"b", "a"}},
+		{map[string]int{"a": 2, "c": 1}, []string{"b", "a"}},
+		{map[string]int{"a": 2, "b": 1, "c": 3}, []string{"b", "a"}},
+		{map[string]int{"a": 2, "b": 1}, []string{"b"}},
+	}
+	for _, c := range cases {
+		if o.EqualToMap(c.m, c.order, eq) {
+			t.Error("EqualToMap accepted", c.m, c.order)
+		}
+	}
+}
+
+func TestOrderedMap_IsReorderOf(t *testing.T) {
+	build := func(pairs ...interface{}) *OrderedMap[int] {
+		o := New[int]()
+		for i := 0; i < len(pairs); i += 2 {
+			o.Set(pairs[i].(string), pairs[i+1].(int))
+		}
+		return o
+	}
+	eq := func(a, b int) bool { return a == b }
+	o := build("a", 1, "b", 2, "c", 3)
+	cases := []struct {
+		other *OrderedMap[int]
+		want  bool
+	}{
+		{build("c", 3, "a", 1, "b", 2), true},
+		{build("a", 1, "b", 2, "c", 3), false},
+		{build("c", 3, "a", 1, "b", 4), false},
+		{build("c", 3, "a", 1, "d", 2), false},
+		{build("c", 3, "a", 1), false},
+	}
+	for i, c := range cases {
+		if got := o.IsReorderOf(c.other, eq); got != c.want {
+			t.Errorf("case %d: IsReorderOf = %v, want %v", i, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package orderedmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Flatten Return a single level map whose keys are the paths of the leaf
+// values, joined by sep, in depth-first order. Nested *OrderedMap[interface{}]
+// and []interface{} values are walked, slice elements being keyed by their
+// index. Empty maps and slices are kept as leaves.
+func (o *OrderedMap[T]) Flatten(sep string) *OrderedMap[interface{}] {
+	flat := New[interface{}]()
+	flat.escapeHTML = o.escapeHTML
+	for _, k := range o.keys {
+		flattenValue(flat, k, o.values[k], sep)
+	}
+	return flat
+}
+
+func flattenValue(flat *OrderedMap[interface{}], path string, v interface{}, sep string) {
+	switch value := v.(type) {
+	case *OrderedMap[interface{}]:
+		if value != nil && len(value.keys) > 0 {
+			for _, k := range value.keys {
+				flattenValue(flat, path+sep+k, value.values[k], sep)
+			}
+			return
+		}
+	case []interface{}:
+		if len(value) > 0 {
+			for i, e := range value {
+				flattenValue(flat, path+sep+strconv.Itoa(i), e, sep)
+			}
+			return
+		}
+	}
+	flat.Set(path, v)
+}
+
+// Unflatten Rebuild the nested structure from a map produced by Flatten.
+// Keys are split on sep; below the first level a segment that is an array
+// index creates a []interface{}, any other segment an *OrderedMap[interface{}].
+// Indexes of a slice must appear in order, starting from 0.
+func (o *OrderedMap[T]) Unflatten(sep string) (*OrderedMap[interface{}], error) {
+	nested := New[interface{}]()
+	nested.escapeHTML = o.escapeHTML
+	for _, k := range o.keys {
+		parts := strings.Split(k, sep)
+		child, _ := nested.Get(parts[0])
+		child, err := unflattenValue(child, parts[1:], o.values[k], o.escapeHTML)
+		if err != nil {
+			return nil, fmt.Errorf("orderedmap: unflatten %q: %w", k, err)
+		}
+		nested.Set(parts[0], child)
+	}
+	return nested, nil
+}
+
+func unflattenValue(node interface{}, parts []string, v interface{}, escapeHTML htmlEscaping) (interface{}, error) {
+	if len(parts) == 0 {
+		if node != nil {
+			return nil, fmt.Errorf("path is already in use")
+		}
+		return v, nil
+	}
+	part := parts[0]
+	if node == nil {
+		if _, err := strconv.ParseUint(part, 10, 0); err == nil {
+			node = []interface{}{}
+		} else {
+			m := New[interface{}]()
+			m.escapeHTML = escapeHTML
+			node = m
+		}
+	}
+	switch container := node.(type) {
+	case *OrderedMap[interface{}]:
+		child, _ := container.Get(part)
+		child, err := unflattenValue(child, parts[1:], v, escapeHTML)
+		if err != nil {
+			return nil, err
+		}
+		container.Set(part, child)
+		return container, nil
+	case []interface{}:
+		i, err := strconv.ParseUint(part, 10, 0)
+		if err != nil || i > uint64(len(container)) {
+			return nil, fmt.Errorf("unexpected array index %q", part)
+		}
+		if i == uint64(len(container)) {
+			container = append(container, nil)
+		}
+		if container[i], err = unflattenValue(container[i], parts[1:], v, escapeHTML); err != nil {
+			return nil, err
+		}
+		return container, nil
+	}
+	return nil, fmt.Errorf("path is already in use by a %T", node)
+}
@@ -0,0 +1,65 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOrderedMap_Flatten(t *testing.T) {
+	s := `{"z":1,"a":{"y":[{"c":true},2],"b":null},"e":{},"f":[]}`
+	o := New[interface{}]()
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	flat := o.Flatten(".")
+	b, err := json.Marshal(flat)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	expected := `{"z":1,"a.y.0.c":true,"a.y.1":2,"a.b":null,"e":{},"f":[]}`
+	if string(b) != expected {
+		t.Error("Flatten result is incorrect", string(b))
+	}
+
+	nested, err := flat.Unflatten(".")
+	if err != nil {
+		t.Fatal("Unflatten error", err)
+	}
+	b, err = json.Marshal(nested)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	if string(b) != s {
+		t.Error("Unflatten result is incorrect", string(b))
+	}
+}
+
+func TestOrderedMap_UnflattenConflict(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("a.b", 1)
+	o.Set("a.b.c", 2)
+	if _, err := o.Unflatten("."); err == nil {
+		t.Error("Unflatten accepted a leaf used as a container")
+	}
+	o = New[interface{}]()
+	o.Set("a.1", 1)
+	if _, err := o.Unflatten("."); err == nil {
+		t.Error("Unflatten accepted a sparse array index")
+	}
+}
+
+func TestOrderedMap_FlattenEdgeCases(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("a", (*OrderedMap[interface{}])(nil))
+	flat := o.Flatten(".")
+	if v, ok := flat.Get("a"); !ok || v.(*OrderedMap[interface{}]) != nil {
+		t.Error("Flatten of a nil map", flat.Keys())
+	}
+	for _, k := range []string{"a.9223372036854775808", "a.18446744073709551615", "a.0.4294967296"} {
+		o = New[interface{}]()
+		o.Set(k, 1)
+		if _, err := o.Unflatten("."); err == nil {
+			t.Errorf("Unflatten accepted %q", k)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package orderedmap
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Inline wraps an ordered map whose entries are spliced into the ordered map
+// holding it, at the position of the Inline value, instead of being nested
+// under its key. The key the Inline value is stored under is not emitted.
+// Inlined keys must not collide with the other keys of the enclosing object,
+// including keys inlined from other Inline values; marshaling returns an error
+// otherwise. Inline values can be nested.
+//
+// encoding/json cannot splice fields, so an Inline value held by anything but
+// an ordered map, such as a struct field, is marshaled as a regular object.
+type Inline[T any] struct {
+	*OrderedMap[T]
+}
+
+// inliner is implemented by Inline values.
+type inliner interface {
+	reserveKeys(used map[string]bool) error
+	encodeInline(buf *bytes.Buffer, state *encodeState, n int) (int, error)
+}
+
+func (in Inline[T]) MarshalJSON() ([]byte, error) {
+	if in.OrderedMap == nil {
+		return []byte("null"), nil
+	}
+	return in.OrderedMap.MarshalJSON()
+}
+
+func (in Inline[T]) encodeJSON(buf *bytes.Buffer, state *encodeState) error {
+	if in.OrderedMap == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	return in.OrderedMap.encodeJSON(buf, state)
+}
+
+// reserveKeys adds the keys spliced by in to used, failing on a key already
+// in use.
+func (in Inline[T]) reserveKeys(used map[string]bool) error {
+	if in.OrderedMap == nil {
+		return nil
+	}
+	for _, k := range in.keys {
+		if nested, ok := interface{}(in.values[k]).(inliner); ok {
+			if err := nested.reserveKeys(used); err != nil {
+				return err
+			}
+			continue
+		}
+		if used[k] {
+			return fmt.Errorf("orderedmap: inlined key %q collides with an existing key", k)
+		}
+		used[k] = true
+	}
+	return nil
+}
+
+func (in Inline[T]) encodeInline(buf *bytes.Buffer, state *encodeState, n int) (int, error) {
+	if in.OrderedMap == nil {
+		return n, nil
+	}
+	return in.OrderedMap.encodeEntries(buf, state, in.keys, nil, n)
+}
@@ -0,0 +1,62 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	meta := New[interface{}]()
+	meta.Set("created", "today")
+	meta.Set("author", "me")
+	extra := New[int]()
+	extra.Set("x", 1)
+	meta.Set("extra", Inline[int]{extra})
+
+	o := New[interface{}]()
+	o.Set("id", 1)
+	o.Set("meta", Inline[interface{}]{meta})
+	o.Set("empty", Inline[interface{}]{New[interface{}]()})
+	o.Set("none", Inline[interface{}]{})
+	o.Set("name", "n")
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	if string(b) != `{"id":1,"created":"today","author":"me","x":1,"name":"n"}` {
+		t.Error("Inline value is incorrect", string(b))
+	}
+
+	// outside of an ordered map an Inline value is a regular object
+	b, err = json.Marshal(struct {
+		Meta Inline[interface{}] `json:"meta"`
+	}{Inline[interface{}]{meta}})
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	if string(b) != `{"meta":{"created":"today","author":"me","x":1}}` {
+		t.Error("Inline struct field value is incorrect", string(b))
+	}
+	b, err = json.Marshal([]interface{}{Inline[interface{}]{}})
+	if err != nil || string(b) != `[null]` {
+		t.Error("Nil Inline value", string(b), err)
+	}
+}
+
+func TestInlineCollision(t *testing.T) {
+	inner := New[int]()
+	inner.Set("name", 1)
+	o := New[interface{}]()
+	o.Set("inline", Inline[int]{inner})
+	o.Set("name", "n")
+	if _, err := json.Marshal(o); err == nil {
+		t.Error("Marshal accepted an inlined key colliding with a parent key")
+	}
+
+	o = New[interface{}]()
+	o.Set("first", Inline[int]{inner})
+	o.Set("second", Inline[int]{inner})
+	if _, err := json.Marshal(o); err == nil {
+		t.Error("Marshal accepted the same key inlined twice")
+	}
+}
@@ -0,0 +1,415 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyPatch Apply a JSON Patch (RFC 6902) document to the map.
+// Paths are JSON Pointers into nested *OrderedMap[interface{}] values and
+// []interface{} slices. Adding a new object member appends it, replacing a
+// member keeps its position and removing one keeps the order of the others.
+// The operations are applied to a copy, so the map is only updated when every
+// operation succeeds.
+func (o *OrderedMap[T]) ApplyPatch(ops []byte) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	var operations []patchOperation
+	if err := json.Unmarshal(ops, &operations); err != nil {
+		return err
+	}
+
+	c := o.decodeConfig()
+	doc, err := o.patchDocument()
+	if err != nil {
+		return err
+	}
+	var root interface{} = doc
+	for i, op := range operations {
+		if root, err = applyPatchOperation(root, op, c); err != nil {
+			return fmt.Errorf("orderedmap: patch operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	doc, ok := root.(*OrderedMap[interface{}])
+	if !ok {
+		return fmt.Errorf("orderedmap: patch result is not an object")
+	}
+
+	values := make(map[string]T, len(doc.values))
+	for _, k := range doc.keys {
+		if _, exists := o.values[k]; !exists {
+			if err := o.validateKey(k); err != nil {
+				return err
+			}
+		}
+		var value T
+		if p, ok := any(&value).(*interface{}); ok {
+			*p = doc.values[k]
+		} else {
+			b, err := json.Marshal(doc.values[k])
+			if err != nil {
+				return err
+			}
+			if err = c.newDecoder(b).Decode(&value); err != nil {
+				return err
+			}
+		}
+		values[k] = value
+	}
+	o.keys = doc.keys
+	o.values = values
+	return nil
+}
+
+// patchDocument returns a copy of the map as a generic JSON document.
+func (o *OrderedMap[T]) patchDocument() (*OrderedMap[interface{}], error) {
+	c := o.decodeConfig()
+	doc := New[interface{}]()
+	doc.escapeHTML = o.escapeHTML
+	for _, k := range o.keys {
+		var value interface{} = o.values[k]
+		if _, ok := any(&o.values).(*map[string]interface{}); ok {
+			value = deepCopyValue(value)
+		} else {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			if value, err = decodeValue(c.newDecoder(b), c); err != nil {
+				return nil, err
+			}
+		}
+		doc.Set(k, value)
+	}
+	return doc, nil
+}
+
+func applyPatchOperation(root interface{}, op patchOperation, c decodeConfig) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("missing path")
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		if value, err = decodeValue(c.newDecoder(op.Value), c); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("missing from")
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = pointerGet(root, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			value = deepCopyValue(value)
+			break
+		}
+		if *op.Path == *op.From {
+			return root, nil
+		}
+		if strings.HasPrefix(*op.Path, *op.From+"/") {
+			return nil, fmt.Errorf("cannot move %q into itself", *op.From)
+		}
+		if root, err = pointerRemove(root, from); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return pointerAdd(root, path, value)
+	case "remove":
+		return pointerRemove(root, path)
+	case "replace":
+		return pointerReplace(root, path, value)
+	case "test":
+		current, err := pointerGet(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !valuesEqual(current, value, false) {
+			return nil, fmt.Errorf("test failed at %q", *op.Path)
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses a pointer token addressing an element of an array of
+// length n. When appendOK is true the token "-" and the index n are accepted.
+func arrayIndex(token string, n int, appendOK bool) (int, error) {
+	if appendOK && token == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > n || (i == n && !appendOK) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := doc.(type) {
+		case *OrderedMap[interface{}]:
+			value, ok := container.Get(token)
+			if !ok {
+				return nil, fmt.Errorf("missing key %q", token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[i]
+		default:
+			return nil, fmt.Errorf("cannot traverse %T with %q", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate walks to the parent of the value addressed by path, lets fn
+// update the parent and stores the returned container back into its own
+// parent. It returns the updated document.
+func pointerUpdate(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	token := path[0]
+	switch container := doc.(type) {
+	case *OrderedMap[interface{}]:
+		child, ok := container.Get(token)
+		if !ok {
+			return nil, fmt.Errorf("missing key %q", token)
+		}
+		child, err := pointerUpdate(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container.Set(token, child)
+		return container, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		if container[i], err = pointerUpdate(container[i], path[1:], fn); err != nil {
+			return nil, err
+		}
+		return container, nil
+	}
+	return nil, fmt.Errorf("cannot traverse %T with %q", doc, token)
+}
+
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case *OrderedMap[interface{}]:
+			container.Set(token, value)
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[i+1:], container[i:])
+			container[i] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to %T", token, parent)
+	})
+}
+
+func pointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the root")
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case *OrderedMap[interface{}]:
+			if _, ok := container.Get(token); !ok {
+				return nil, fmt.Errorf("missing key %q", token)
+			}
+			container.Delete(token)
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:i:i], container[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from %T", token, parent)
+	})
+}
+
+func pointerReplace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case *OrderedMap[interface{}]:
+			if _, ok := container.Get(token); !ok {
+				return nil, fmt.Errorf("missing key %q", token)
+			}
+			container.Set(token, value)
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			container[i] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot replace %q in %T", token, parent)
+	})
+}
+
+// deepCopyValue copies nested *OrderedMap[interface{}], []interface{} and
+// map[string]interface{} values. Other values are returned as is. A value
+// held several times, or holding itself, is copied once and the copy keeps
+// the same structure.
+func deepCopyValue(v interface{}) interface{} {
+	return deepCopy(v, map[copyKey]interface{}{})
+}
+
+// replaceMapRefs replaces, in v and the containers it holds, every reference
+// to old by repl.
+func replaceMapRefs(v interface{}, old, repl *OrderedMap[interface{}], visited map[uintptr]bool) interface{} {
+	if v == old {
+		return repl
+	}
+	switch value := v.(type) {
+	case *OrderedMap[interface{}]:
+		if value == nil || visited[reflect.ValueOf(value).Pointer()] {
+			break
+		}
+		visited[reflect.ValueOf(value).Pointer()] = true
+		for k, e := range value.values {
+			value.values[k] = replaceMapRefs(e, old, repl, visited)
+		}
+	case map[string]interface{}:
+		if value == nil || visited[reflect.ValueOf(value).Pointer()] {
+			break
+		}
+		visited[reflect.ValueOf(value).Pointer()] = true
+		for k, e := range value {
+			value[k] = replaceMapRefs(e, old, repl, visited)
+		}
+	case []interface{}:
+		if len(value) == 0 || visited[reflect.ValueOf(value).Pointer()] {
+			break
+		}
+		visited[reflect.ValueOf(value).Pointer()] = true
+		for i, e := range value {
+			value[i] = replaceMapRefs(e, old, repl, visited)
+		}
+	}
+	return v
+}
+
+// copyKey identifies a container already copied by deepCopy.
+type copyKey struct {
+	p uintptr
+	n int
+}
+
+func deepCopy(v interface{}, copies map[copyKey]interface{}) interface{} {
+	switch value := v.(type) {
+	case *OrderedMap[interface{}]:
+		if value == nil {
+			return value
+		}
+		key := copyKey{reflect.ValueOf(value).Pointer(), -1}
+		if c, ok := copies[key]; ok {
+			return c
+		}
+		c := value.clone()
+		copies[key] = c
+		for k, e := range c.values {
+			c.values[k] = deepCopy(e, copies)
+		}
+		return c
+	case map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		key := copyKey{reflect.ValueOf(value).Pointer(), -2}
+		if c, ok := copies[key]; ok {
+			return c
+		}
+		c := make(map[string]interface{}, len(value))
+		copies[key] = c
+		for k, e := range value {
+			c[k] = deepCopy(e, copies)
+		}
+		return c
+	case []interface{}:
+		if value == nil {
+			return value
+		}
+		if len(value) == 0 {
+			return []interface{}{}
+		}
+		key := copyKey{reflect.ValueOf(value).Pointer(), len(value)}
+		if c, ok := copies[key]; ok {
+			return c
+		}
+		c := make([]interface{}, len(value))
+		copies[key] = c
+		for i := range value {
+			c[i] = deepCopy(value[i], copies)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,101 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOrderedMap_ApplyPatch(t *testing.T) {
+	s := `{"b":1,"a":{"y":[1,2,3],"x":"x"},"c":"c"}`
+	o := New[interface{}]()
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	ops := `[
+		{"op":"add","path":"/d","value":{"k":"v"}},
+		{"op":"add","path":"/a/y/1","value":9},
+		{"op":"add","path":"/a/y/-","value":4},
+		{"op":"remove","path":"/b"},
+		{"op":"replace","path":"/c","value":"C"},
+		{"op":"move","from":"/a/x","path":"/d/x"},
+		{"op":"copy","from":"/d","path":"/e~1f"},
+		{"op":"test","path":"/a/y","value":[1,9,2,3,4]}
+	]`
+	if err := o.ApplyPatch([]byte(ops)); err != nil {
+		t.Fatal("ApplyPatch error", err)
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	expected := `{"a":{"y":[1,9,2,3,4]},"c":"C","d":{"k":"v","x":"x"},"e/f":{"k":"v","x":"x"}}`
+	if string(b) != expected {
+		t.Error("ApplyPatch result is incorrect", string(b))
+	}
+}
+
+func TestOrderedMap_ApplyPatchAtomic(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	ops := `[
+		{"op":"remove","path":"/a"},
+		{"op":"test","path":"/b","value":3}
+	]`
+	if err := o.ApplyPatch([]byte(ops)); err == nil {
+		t.Error("ApplyPatch did not fail on a failing test operation")
+	}
+	if len(o.Keys()) != 2 {
+		t.Error("ApplyPatch modified the map on error", o.Keys())
+	}
+	for _, bad := range []string{
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/a/b","value":1}]`,
+		`[{"op":"unknown","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+	} {
+		if err := o.ApplyPatch([]byte(bad)); err == nil {
+			t.Error("ApplyPatch accepted", bad)
+		}
+	}
+}
+
+func TestOrderedMap_ApplyPatchTyped(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	ops := `[{"op":"remove","path":"/a"},{"op":"add","path":"/d","value":4},{"op":"move","from":"/b","path":"/e"}]`
+	if err := o.ApplyPatch([]byte(ops)); err != nil {
+		t.Fatal("ApplyPatch error", err)
+	}
+	b, _ := json.Marshal(o)
+	if string(b) != `{"c":3,"d":4,"e":2}` {
+		t.Error("ApplyPatch result is incorrect", string(b))
+	}
+}
+
+func TestOrderedMap_ApplyPatchTest(t *testing.T) {
+	o := New[interface{}]()
+	o.SetUseNumber(true)
+	if err := json.Unmarshal([]byte(`{"id":123456789012345678}`), o); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ApplyPatch([]byte(`[{"op":"test","path":"/id","value":123456789012345679}]`)); err == nil {
+		t.Error("test passed against a different large ID")
+	}
+	if err := o.ApplyPatch([]byte(`[{"op":"test","path":"/id","value":123456789012345678}]`)); err != nil {
+		t.Error("test failed against the same large ID", err)
+	}
+
+	o = New[interface{}]()
+	o.Set("m", map[string]interface{}{"b": 1, "a": []string{"x"}})
+	o.Set("s", []string{"y", "z"})
+	if err := o.ApplyPatch([]byte(`[{"op":"test","path":"/m","value":{"a":["x"],"b":1}},{"op":"test","path":"/s","value":["y","z"]}]`)); err != nil {
+		t.Error("test failed on values stored by the caller", err)
+	}
+	if err := o.ApplyPatch([]byte(`[{"op":"test","path":"/s","value":["z","y"]}]`)); err == nil {
+		t.Error("test ignored the order of an array")
+	}
+}
@@ -0,0 +1,43 @@
+package orderedmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToKVStrings Return the entries as key, sep and value joined in a string, in
+// order, such as "A=1" for sep "=". Values are formatted with fmt.Sprint.
+func (o *OrderedMap[T]) ToKVStrings(sep string) []string {
+	kv := make([]string, len(o.keys))
+	for i, k := range o.keys {
+		var value interface{} = o.values[k]
+		s, ok := value.(string)
+		if !ok {
+			s = fmt.Sprint(value)
+		}
+		kv[i] = k + sep + s
+	}
+	return kv
+}
+
+// FromKVLines Build a map from lines such as "A=1" for sep "=", in line order.
+// Each line is split on its first sep and spaces around the key and the value
+// are trimmed. Blank lines and lines starting with # are skipped. A repeated
+// key keeps its last value and takes the position of its last occurrence. It
+// returns an error for a line without sep or with an empty key.
+func FromKVLines(lines []string, sep string) (*OrderedMap[string], error) {
+	o := New[string]()
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, found := strings.Cut(line, sep)
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("orderedmap: malformed line %d: %q", i+1, line)
+		}
+		o.setLast(key, strings.TrimSpace(value))
+	}
+	return o, nil
+}
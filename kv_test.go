@@ -0,0 +1,42 @@
+package orderedmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrderedMap_ToKVStrings(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("B", 2)
+	o.Set("A", "x=y")
+	o.Set("C", true)
+	if kv := o.ToKVStrings("="); strings.Join(kv, " ") != "B=2 A=x=y C=true" {
+		t.Error("ToKVStrings is incorrect", kv)
+	}
+	if kv := New[string]().ToKVStrings("="); kv == nil || len(kv) != 0 {
+		t.Error("ToKVStrings of an empty map", kv)
+	}
+}
+
+func TestFromKVLines(t *testing.T) {
+	o, err := FromKVLines([]string{
+		"# settings",
+		"HOST = example.com",
+		"",
+		"PORT=80",
+		"URL=http://x/?a=b",
+		"HOST=localhost",
+	}, "=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv := o.ToKVStrings("="); strings.Join(kv, " ") != "PORT=80 URL=http://x/?a=b HOST=localhost" {
+		t.Error("FromKVLines is incorrect", kv)
+	}
+	if _, err = FromKVLines([]string{"A=1", "B"}, "="); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Error("FromKVLines accepted a line without separator", err)
+	}
+	if _, err = FromKVLines([]string{"=1"}, "="); err == nil {
+		t.Error("FromKVLines accepted an empty key")
+	}
+}
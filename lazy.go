@@ -0,0 +1,137 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// LazyOrderedMap is an ordered map decoding its values on demand: UnmarshalJSON
+// records the keys in order but keeps each value as raw JSON until it is first
+// read by Get, which decodes it as OrderedMap.UnmarshalJSON would and caches
+// the result. Values never read are marshaled back as they were received.
+// The zero value is an empty map ready to use.
+type LazyOrderedMap[T any] struct {
+	// m holds the keys and the decoded values, the zero value of T standing
+	// for the values still in raw.
+	m   OrderedMap[T]
+	raw map[string]json.RawMessage
+}
+
+func NewLazy[T any]() *LazyOrderedMap[T] {
+	return &LazyOrderedMap[T]{}
+}
+
+// SetEscapeHTML Set whether problematic HTML characters are escaped when the
+// map is marshaled, as OrderedMap.SetEscapeHTML does.
+func (l *LazyOrderedMap[T]) SetEscapeHTML(on bool) {
+	l.m.SetEscapeHTML(on)
+}
+
+// SetUseNumber Set whether numbers are decoded as json.Number, as
+// OrderedMap.SetUseNumber does.
+func (l *LazyOrderedMap[T]) SetUseNumber(on bool) {
+	l.m.SetUseNumber(on)
+}
+
+// Get Return the value of key, decoding it on first access. The error is
+// that of decoding; the value stays raw when it fails.
+func (l *LazyOrderedMap[T]) Get(key string) (T, bool, error) {
+	raw, pending := l.raw[key]
+	if !pending {
+		val, exists := l.m.Get(key)
+		return val, exists, nil
+	}
+	var value T
+	c := l.m.decodeConfig()
+	var err error
+	if p, ok := any(&value).(*interface{}); ok {
+		*p, err = decodeValue(c.newDecoder(raw), c)
+	} else {
+		err = c.newDecoder(raw).Decode(&value)
+	}
+	if err != nil {
+		return value, true, err
+	}
+	l.m.values[key] = value
+	delete(l.raw, key)
+	return value, true, nil
+}
+
+// Set Set the value of key, appending key when it is not in use.
+func (l *LazyOrderedMap[T]) Set(key string, value T) {
+	l.m.Set(key, value)
+	delete(l.raw, key)
+}
+
+func (l *LazyOrderedMap[T]) Delete(key string) {
+	l.m.Delete(key)
+	delete(l.raw, key)
+}
+
+// Len Return the number of entries.
+func (l *LazyOrderedMap[T]) Len() int {
+	return len(l.m.keys)
+}
+
+// Keys Return the keys in order. The slice must not be modified.
+func (l *LazyOrderedMap[T]) Keys() []string {
+	return l.m.Keys()
+}
+
+// Pending Return the number of values not decoded yet.
+func (l *LazyOrderedMap[T]) Pending() int {
+	return len(l.raw)
+}
+
+// ToOrderedMap Decode the remaining values and return the entries as an
+// OrderedMap with the same settings.
+func (l *LazyOrderedMap[T]) ToOrderedMap() (*OrderedMap[T], error) {
+	for _, k := range l.m.keys {
+		if _, _, err := l.Get(k); err != nil {
+			return nil, err
+		}
+	}
+	o := l.m.clone()
+	o.initValues()
+	return o, nil
+}
+
+// MarshalJSON Marshal the map in order, copying the values not decoded yet
+// as they were received.
+func (l *LazyOrderedMap[T]) MarshalJSON() ([]byte, error) {
+	o := New[interface{}]()
+	o.escapeHTML = l.m.escapeHTML
+	for _, k := range l.m.keys {
+		if raw, pending := l.raw[k]; pending {
+			o.Set(k, raw)
+		} else {
+			o.Set(k, l.m.values[k])
+		}
+	}
+	return o.MarshalJSON()
+}
+
+// UnmarshalJSON Record the keys of a JSON object in order and keep the values
+// raw, replacing the entries of the map. Duplicate keys take the position of
+// their last occurrence, null empties the map and data following the object
+// is rejected, as in OrderedMap.
+func (l *LazyOrderedMap[T]) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	var zero T
+	l.m.keys = []string{}
+	l.m.values = map[string]T{}
+	l.raw = map[string]json.RawMessage{}
+	if string(bytes.TrimSpace(b)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	err := streamDecode(dec, func(key string, value json.RawMessage) error {
+		l.m.setLast(key, zero)
+		l.raw[key] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return expectEOF(dec)
+}
@@ -0,0 +1,73 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLazyOrderedMap(t *testing.T) {
+	l := NewLazy[interface{}]()
+	data := `{"b":{"y":1,"x":2},"a":[1, 2],"c":"<c>","b":{"z":3,"y":1}}`
+	if err := json.Unmarshal([]byte(data), l); err != nil {
+		t.Fatal(err)
+	}
+	if k := l.Keys(); len(k) != 3 || k[0] != "a" || k[1] != "c" || k[2] != "b" {
+		t.Error("LazyOrderedMap keys", k)
+	}
+	if l.Pending() != 3 {
+		t.Error("LazyOrderedMap decoded values eagerly", l.Pending())
+	}
+	v, ok, err := l.Get("b")
+	if err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if m, isMap := v.(*OrderedMap[interface{}]); !isMap || m.Keys()[0] != "z" {
+		t.Errorf("LazyOrderedMap Get decoded %#v", v)
+	}
+	if l.Pending() != 2 {
+		t.Error("LazyOrderedMap Get did not cache the value", l.Pending())
+	}
+	if _, ok, _ = l.Get("missing"); ok {
+		t.Error("LazyOrderedMap Get found a missing key")
+	}
+	l.Set("d", 4)
+	l.Delete("c")
+	b, err := l.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != `{"a":[1,2],"b":{"z":3,"y":1},"d":4}` {
+		t.Error("LazyOrderedMap MarshalJSON", s)
+	}
+	o, err := l.ToOrderedMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Pending() != 0 || len(o.Keys()) != 3 {
+		t.Error("LazyOrderedMap ToOrderedMap", o.Keys())
+	}
+}
+
+func TestLazyOrderedMapDecodeError(t *testing.T) {
+	var l LazyOrderedMap[int]
+	if err := l.UnmarshalJSON([]byte(`{"a":1,"b":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, err := l.Get("a"); err != nil || v != 1 {
+		t.Error("LazyOrderedMap Get", v, err)
+	}
+	if _, ok, err := l.Get("b"); !ok || err == nil {
+		t.Error("LazyOrderedMap Get did not report the decoding error")
+	}
+	if err := l.UnmarshalJSON([]byte(`null`)); err != nil || l.Len() != 0 {
+		t.Error("LazyOrderedMap null", err, l.Len())
+	}
+	for _, s := range []string{`{"a":1} garbage`, `{"a":1}{"b":2}`, `null x`} {
+		if err := l.UnmarshalJSON([]byte(s)); err == nil {
+			t.Errorf("LazyOrderedMap UnmarshalJSON accepted %q", s)
+		}
+	}
+	if err := l.UnmarshalJSON([]byte("{\"a\":1}\n")); err != nil {
+		t.Error("LazyOrderedMap rejected trailing white space", err)
+	}
+}
@@ -0,0 +1,182 @@
+package orderedmap
+
+// LinkedOrderedMap is an ordered map keeping its keys in a doubly linked list,
+// so that deleting and moving keys take constant time, where OrderedMap has to
+// shift its key slice. Keys returns a new slice on every call. It marshals to
+// and from JSON like OrderedMap. The zero value is an empty map ready to use;
+// a map must not be copied after first use.
+type LinkedOrderedMap[T any] struct {
+	nodes map[string]*linkedNode[T]
+	// root is the sentinel of the circular list: root.next is the first
+	// node and root.prev the last one.
+	root       linkedNode[T]
+	escapeHTML htmlEscaping
+}
+
+type linkedNode[T any] struct {
+	key        string
+	value      T
+	prev, next *linkedNode[T]
+}
+
+func NewLinked[T any]() *LinkedOrderedMap[T] {
+	l := &LinkedOrderedMap[T]{}
+	l.init()
+	return l
+}
+
+// init prepares a zero value map for use.
+func (l *LinkedOrderedMap[T]) init() {
+	if l.nodes == nil {
+		l.nodes = map[string]*linkedNode[T]{}
+		l.root.next = &l.root
+		l.root.prev = &l.root
+	}
+}
+
+// SetEscapeHTML Set whether problematic HTML characters are escaped when the
+// map is marshaled, as OrderedMap.SetEscapeHTML does.
+func (l *LinkedOrderedMap[T]) SetEscapeHTML(on bool) {
+	l.escapeHTML = htmlEscaping{skipKeys: !on, skipValues: !on}
+}
+
+func (l *LinkedOrderedMap[T]) Get(key string) (T, bool) {
+	if n, ok := l.nodes[key]; ok {
+		return n.value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Set Set the value of key, appending key when it is not in use.
+func (l *LinkedOrderedMap[T]) Set(key string, value T) {
+	l.init()
+	if n, ok := l.nodes[key]; ok {
+		n.value = value
+		return
+	}
+	n := &linkedNode[T]{key: key, value: value}
+	l.nodes[key] = n
+	l.insertAfter(n, l.root.prev)
+}
+
+// Delete Remove key in constant time. Keys not in use are ignored.
+func (l *LinkedOrderedMap[T]) Delete(key string) {
+	if n, ok := l.nodes[key]; ok {
+		l.unlink(n)
+		delete(l.nodes, key)
+	}
+}
+
+// Len Return the number of entries.
+func (l *LinkedOrderedMap[T]) Len() int {
+	return len(l.nodes)
+}
+
+// Keys Return a new slice holding the keys in order.
+func (l *LinkedOrderedMap[T]) Keys() []string {
+	keys := make([]string, 0, len(l.nodes))
+	l.Each(func(key string, _ T) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Each Call fn for every entry in order. Iteration stops when fn returns
+// false. fn must not modify the map.
+func (l *LinkedOrderedMap[T]) Each(fn func(key string, value T) bool) {
+	if l.nodes == nil {
+		return
+	}
+	for n := l.root.next; n != &l.root; n = n.next {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// MoveToFront Move key to the front in constant time. It reports whether key
+// is in use.
+func (l *LinkedOrderedMap[T]) MoveToFront(key string) bool {
+	return l.move(key, &l.root)
+}
+
+// MoveToBack Move key to the back in constant time. It reports whether key is
+// in use.
+func (l *LinkedOrderedMap[T]) MoveToBack(key string) bool {
+	return l.move(key, l.root.prev)
+}
+
+// MoveAfter Move key right after mark in constant time. It reports whether
+// both keys are in use.
+func (l *LinkedOrderedMap[T]) MoveAfter(key, mark string) bool {
+	m, ok := l.nodes[mark]
+	return ok && l.move(key, m)
+}
+
+// MoveBefore Move key right before mark in constant time. It reports whether
+// both keys are in use.
+func (l *LinkedOrderedMap[T]) MoveBefore(key, mark string) bool {
+	m, ok := l.nodes[mark]
+	return ok && l.move(key, m.prev)
+}
+
+// move moves the node of key after at.
+func (l *LinkedOrderedMap[T]) move(key string, at *linkedNode[T]) bool {
+	n, ok := l.nodes[key]
+	if !ok {
+		return false
+	}
+	if n == at || n.prev == at {
+		return true
+	}
+	l.unlink(n)
+	l.insertAfter(n, at)
+	return true
+}
+
+func (l *LinkedOrderedMap[T]) insertAfter(n, at *linkedNode[T]) {
+	n.prev = at
+	n.next = at.next
+	at.next.prev = n
+	at.next = n
+}
+
+func (l *LinkedOrderedMap[T]) unlink(n *linkedNode[T]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+}
+
+// ToOrderedMap Return the entries as an OrderedMap with the same settings.
+func (l *LinkedOrderedMap[T]) ToOrderedMap() *OrderedMap[T] {
+	o := New[T]()
+	o.escapeHTML = l.escapeHTML
+	l.Each(func(key string, value T) bool {
+		o.keys = append(o.keys, key)
+		o.values[key] = value
+		return true
+	})
+	return o
+}
+
+func (l *LinkedOrderedMap[T]) MarshalJSON() ([]byte, error) {
+	return l.ToOrderedMap().MarshalJSON()
+}
+
+// UnmarshalJSON Decode a JSON object as OrderedMap.UnmarshalJSON does,
+// replacing the entries of the map.
+func (l *LinkedOrderedMap[T]) UnmarshalJSON(b []byte) error {
+	o := New[T]()
+	o.escapeHTML = l.escapeHTML
+	if err := o.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	l.nodes = nil
+	l.init()
+	for _, k := range o.keys {
+		l.Set(k, o.values[k])
+	}
+	return nil
+}
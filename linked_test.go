@@ -0,0 +1,86 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLinkedOrderedMap(t *testing.T) {
+	l := NewLinked[int]()
+	for i, k := range []string{"a", "b", "c", "d"} {
+		l.Set(k, i)
+	}
+	l.Set("b", 10)
+	l.Delete("c")
+	l.Delete("missing")
+	if k := strings.Join(l.Keys(), " "); k != "a b d" || l.Len() != 3 {
+		t.Error("Linked keys after Set and Delete", k, l.Len())
+	}
+	if v, ok := l.Get("b"); !ok || v != 10 {
+		t.Error("Linked Get", v, ok)
+	}
+	if _, ok := l.Get("c"); ok {
+		t.Error("Linked Get of a deleted key")
+	}
+
+	moves := []struct {
+		move func() bool
+		want string
+	}{
+		{func() bool { return l.MoveToFront("d") }, "d a b"},
+		{func() bool { return l.MoveToBack("d") }, "a b d"},
+		{func() bool { return l.MoveToBack("d") }, "a b d"},
+		{func() bool { return l.MoveBefore("d", "a") }, "d a b"},
+		{func() bool { return l.MoveAfter("d", "a") }, "a d b"},
+		{func() bool { return l.MoveAfter("a", "a") }, "a d b"},
+	}
+	for i, m := range moves {
+		if !m.move() {
+			t.Errorf("move %d failed", i)
+		}
+		if k := strings.Join(l.Keys(), " "); k != m.want {
+			t.Errorf("move %d: keys %s, want %s", i, k, m.want)
+		}
+	}
+	if l.MoveToFront("missing") || l.MoveAfter("a", "missing") {
+		t.Error("moving a missing key succeeded")
+	}
+
+	var visited []string
+	l.Each(func(key string, value int) bool {
+		visited = append(visited, key)
+		return len(visited) < 2
+	})
+	if len(visited) != 2 {
+		t.Error("Each did not stop", visited)
+	}
+}
+
+func TestLinkedOrderedMap_JSON(t *testing.T) {
+	var l LinkedOrderedMap[interface{}]
+	l.Set("<z>", 1)
+	if b, _ := l.MarshalJSON(); string(b) != `{"\u003cz\u003e":1}` {
+		t.Error("zero value MarshalJSON", string(b))
+	}
+	s := `{"b":1,"a":{"d":[1,2],"c":"x"}}`
+	if err := json.Unmarshal([]byte(s), &l); err != nil {
+		t.Fatal("Unmarshal error", err)
+	}
+	if k := strings.Join(l.Keys(), " "); k != "b a" {
+		t.Error("UnmarshalJSON keys", k)
+	}
+	l.SetEscapeHTML(false)
+	l.Set("<", true)
+	b, err := json.Marshal(&l)
+	if err != nil {
+		t.Fatal("Marshal error", err)
+	}
+	// json.Marshal escapes HTML whatever SetEscapeHTML says
+	if string(b) != `{"b":1,"a":{"d":[1,2],"c":"x"},"\u003c":true}` {
+		t.Error("Marshal output is incorrect", string(b))
+	}
+	if b, _ = l.MarshalJSON(); string(b) != `{"b":1,"a":{"d":[1,2],"c":"x"},"<":true}` {
+		t.Error("MarshalJSON output is incorrect", string(b))
+	}
+}
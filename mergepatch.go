@@ -0,0 +1,84 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ApplyMergePatch Apply a JSON Merge Patch (RFC 7386) to the map.
+// Updated keys keep their position, keys patched with null are deleted and
+// new keys are appended in the order they appear in the patch.
+// For an OrderedMap[interface{}] the patch is merged recursively into nested
+// ordered maps; for other value types each patched value replaces the old one.
+// The map is left untouched when the patch cannot be decoded.
+func (o *OrderedMap[T]) ApplyMergePatch(patch []byte) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	p := New[json.RawMessage]()
+	if err := json.Unmarshal(patch, p); err != nil {
+		return err
+	}
+	c := o.decodeConfig()
+	values := make([]T, len(p.keys))
+	for i, k := range p.keys {
+		raw := p.values[k]
+		if isJSONNull(raw) {
+			continue
+		}
+		if _, exists := o.values[k]; !exists {
+			if err := o.validateKey(k); err != nil {
+				return err
+			}
+		}
+		if v, ok := any(&values[i]).(*interface{}); ok {
+			patchValue, err := decodeValue(c.newDecoder(raw), c)
+			if err != nil {
+				return err
+			}
+			current, _ := o.Get(k)
+			*v = mergePatchValue(current, patchValue)
+		} else if err := c.newDecoder(raw).Decode(&values[i]); err != nil {
+			return err
+		}
+	}
+	for i, k := range p.keys {
+		if isJSONNull(p.values[k]) {
+			o.Delete(k)
+		} else {
+			o.Set(k, values[i])
+		}
+	}
+	return nil
+}
+
+// mergePatchValue returns the result of merging patch into target as
+// described by RFC 7386.
+func mergePatchValue(target, patch interface{}) interface{} {
+	p, ok := patch.(*OrderedMap[interface{}])
+	if !ok {
+		return patch
+	}
+	// merge into a copy so the target is untouched until the patch is applied
+	t, ok := target.(*OrderedMap[interface{}])
+	if ok && t != nil {
+		t = t.clone()
+	} else {
+		t = New[interface{}]()
+		t.escapeHTML = p.escapeHTML
+	}
+	for _, k := range p.keys {
+		v := p.values[k]
+		if v == nil {
+			t.Delete(k)
+			continue
+		}
+		current, _ := t.Get(k)
+		t.Set(k, mergePatchValue(current, v))
+	}
+	return t
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
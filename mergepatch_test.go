@@ -0,0 +1,76 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestOrderedMap_ApplyMergePatch(t *testing.T) {
+	s := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	o := New[interface{}]()
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	patch := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null,"nickName":"JD"},"tags":["example"],"extra":{"a":null,"b":1}}`
+	if err := o.ApplyMergePatch([]byte(patch)); err != nil {
+		t.Fatal("ApplyMergePatch error", err)
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	expected := `{"title":"Hello!","author":{"givenName":"John","nickName":"JD"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890","extra":{"b":1}}`
+	if string(b) != expected {
+		t.Error("ApplyMergePatch result is incorrect", string(b))
+	}
+}
+
+func TestOrderedMap_ApplyMergePatchTyped(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	if err := o.ApplyMergePatch([]byte(`{"d":4,"b":null,"a":10}`)); err != nil {
+		t.Fatal("ApplyMergePatch error", err)
+	}
+	b, _ := json.Marshal(o)
+	if string(b) != `{"a":10,"c":3,"d":4}` {
+		t.Error("ApplyMergePatch result is incorrect", string(b))
+	}
+	if err := o.ApplyMergePatch([]byte(`{"a":"x"}`)); err == nil {
+		t.Error("ApplyMergePatch accepted a value of the wrong type")
+	}
+	if v, _ := o.Get("a"); v != 10 {
+		t.Error("ApplyMergePatch modified the map on error", v)
+	}
+}
+
+func TestOrderedMap_ApplyMergePatchError(t *testing.T) {
+	o := New[interface{}]()
+	if err := json.Unmarshal([]byte(`{"n":{"x":1},"ok":2}`), o); err != nil {
+		t.Fatal(err)
+	}
+	nested, _ := o.Get("n")
+	o.SetKeyValidator(func(key string) error {
+		if key == "bad" {
+			return fmt.Errorf("bad key")
+		}
+		return nil
+	})
+	if err := o.ApplyMergePatch([]byte(`{"n":{"x":null,"y":2},"bad":1}`)); err == nil {
+		t.Error("ApplyMergePatch accepted a rejected key")
+	}
+	if b, _ := o.MarshalJSON(); string(b) != `{"n":{"x":1},"ok":2}` {
+		t.Error("ApplyMergePatch modified the map on error", string(b))
+	}
+	if err := o.ApplyMergePatch([]byte(`{"n":{"x":2}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(nested); string(b) != `{"x":1}` {
+		t.Error("ApplyMergePatch modified a nested map in place", string(b))
+	}
+	if b, _ := o.MarshalJSON(); string(b) != `{"n":{"x":2},"ok":2}` {
+		t.Error("ApplyMergePatch result is incorrect", string(b))
+	}
+}
@@ -2,8 +2,16 @@ package orderedmap
 
 import (
 	"bytes"
+	"container/heap"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type Pair[T any] struct {
@@ -28,38 +36,502 @@ func (a ByPair[T]) Len() int           { return len(a.Pairs) }
 func (a ByPair[T]) Swap(i, j int)      { a.Pairs[i], a.Pairs[j] = a.Pairs[j], a.Pairs[i] }
 func (a ByPair[T]) Less(i, j int) bool { return a.LessFunc(a.Pairs[i], a.Pairs[j]) }
 
+// OrderedMap is a map keeping its keys in insertion order. The zero value is
+// an empty map ready to use, with the same settings as one returned by New.
 type OrderedMap[T any] struct {
 	keys       []string
 	values     map[string]T
-	escapeHTML bool
+	escapeHTML htmlEscaping
+	useNumber  bool
+	// intsAsInt64 is set by DecodeIntegersAsInt64
+	intsAsInt64 bool
+	frozen      bool
+	// floatFmt and floatPrec are set by SetFloatFormat
+	floatFmt  byte
+	floatPrec int
+	// keyValidator, when set, is called for every key added to the map
+	keyValidator func(key string) error
+	// validateUTF8 is set by SetValidateUTF8Keys
+	validateUTF8 bool
+	// valueEncoder, when set, is consulted for every value marshaled
+	valueEncoder func(key string, value T) ([]byte, bool, error)
+	// normalizer and collisionPolicy are set by SetKeyNormalizer
+	normalizer      func(key string) string
+	collisionPolicy NormalizeCollisionPolicy
 }
 
 func New[T any]() *OrderedMap[T] {
 	o := OrderedMap[T]{}
 	o.keys = []string{}
 	o.values = map[string]T{}
-	o.escapeHTML = true
 	return &o
 }
 
+// clone returns a shallow copy of the map with the same settings. The copy is
+// not frozen.
+func (o *OrderedMap[T]) clone() *OrderedMap[T] {
+	c := *o
+	c.frozen = false
+	c.keys = make([]string, len(o.keys))
+	copy(c.keys, o.keys)
+	c.values = make(map[string]T, len(o.values))
+	for k, v := range o.values {
+		c.values[k] = v
+	}
+	return &c
+}
+
+// SetEscapeHTML Set whether problematic HTML characters are escaped when the
+// map is marshaled. The setting applies to nested ordered maps as well.
+// Note that json.Marshal and json.MarshalIndent always escape HTML; use
+// MarshalJSON, MarshalIndent or an Encoder with SetEscapeHTML(false) instead.
 func (o *OrderedMap[T]) SetEscapeHTML(on bool) {
-	o.escapeHTML = on
+	o.mustBeMutable()
+	o.escapeHTML = htmlEscaping{skipKeys: !on, skipValues: !on}
+}
+
+// SetEscapeHTMLKeys Like SetEscapeHTML, but only for keys.
+func (o *OrderedMap[T]) SetEscapeHTMLKeys(on bool) {
+	o.mustBeMutable()
+	o.escapeHTML.skipKeys = !on
+}
+
+// SetEscapeHTMLValues Like SetEscapeHTML, but only for string values.
+func (o *OrderedMap[T]) SetEscapeHTMLValues(on bool) {
+	o.mustBeMutable()
+	o.escapeHTML.skipValues = !on
+}
+
+// htmlEscaping says whether HTML characters are left unescaped in keys and
+// values. Its zero value escapes both, as encoding/json does.
+type htmlEscaping struct {
+	skipKeys, skipValues bool
+}
+
+// SetFloatFormat Format float values as strconv.FormatFloat(f, format, prec)
+// does when marshaling, at every nesting level. format must be one of 'e',
+// 'E', 'f', 'g' or 'G', the formats producing JSON numbers; SetFloatFormat
+// panics otherwise. A format of 0 restores the default JSON formatting.
+func (o *OrderedMap[T]) SetFloatFormat(format byte, prec int) {
+	o.mustBeMutable()
+	switch format {
+	case 0, 'e', 'E', 'f', 'g', 'G':
+	default:
+		panic(fmt.Sprintf("orderedmap: invalid float format %q", format))
+	}
+	o.floatFmt = format
+	o.floatPrec = prec
+}
+
+// SetValueEncoder Make MarshalJSON and the other marshaling methods call fn
+// for every value of the map; when fn reports the value as handled, the JSON
+// it returns is written in place of the default encoding. Values of nested
+// maps are encoded by their own value encoder. A nil fn restores the default.
+func (o *OrderedMap[T]) SetValueEncoder(fn func(key string, value T) ([]byte, bool, error)) {
+	o.mustBeMutable()
+	o.valueEncoder = fn
+}
+
+// SetUseNumber Set whether UnmarshalJSON decodes numbers held in interface{}
+// values as json.Number rather than float64. json.Number values are marshaled
+// verbatim, so large integers such as IDs survive a round trip unchanged.
+func (o *OrderedMap[T]) SetUseNumber(on bool) {
+	o.mustBeMutable()
+	o.useNumber = on
+}
+
+// DecodeIntegersAsInt64 Make UnmarshalJSON decode integral numbers held in
+// interface{} values as int64, and other numbers as float64, or json.Number
+// when SetUseNumber is on. Integers overflowing an int64 are decoded as
+// other numbers. It applies to an OrderedMap[interface{}] and the maps
+// nested in it.
+func (o *OrderedMap[T]) DecodeIntegersAsInt64() {
+	o.mustBeMutable()
+	o.intsAsInt64 = true
+}
+
+// ErrFrozen is returned, or used as panic value, when a frozen map is modified.
+var ErrFrozen = errors.New("orderedmap: map is frozen")
+
+// Freeze Make the map read-only. Methods modifying the map panic with
+// ErrFrozen afterwards, or return it when they already return an error.
+// Maps derived from a frozen map, such as the buckets of GroupBy, are not
+// frozen. Values stored in the map are not frozen either.
+func (o *OrderedMap[T]) Freeze() {
+	o.frozen = true
+}
+
+func (o *OrderedMap[T]) IsFrozen() bool {
+	return o.frozen
+}
+
+// mustBeMutable panics with ErrFrozen when the map is frozen.
+func (o *OrderedMap[T]) mustBeMutable() {
+	if o.frozen {
+		panic(ErrFrozen)
+	}
+}
+
+// SetKeyValidator Check every key added to the map with fn. Set panics with
+// the error returned by fn while methods returning an error, such as
+// UnmarshalJSON, return it. Keys of nested maps are not checked.
+// A nil fn removes the validator.
+func (o *OrderedMap[T]) SetKeyValidator(fn func(key string) error) {
+	o.mustBeMutable()
+	o.keyValidator = fn
+}
+
+// SetValidateUTF8Keys Set whether keys added to the map must be valid UTF-8.
+// Invalid keys are rejected like those refused by a key validator: Set panics
+// while SetKey and UnmarshalJSON return an error.
+func (o *OrderedMap[T]) SetValidateUTF8Keys(on bool) {
+	o.mustBeMutable()
+	o.validateUTF8 = on
+}
+
+// NormalizeCollisionPolicy tells Set what to do with a key that is not in use
+// but normalizes to the same key as one that is.
+type NormalizeCollisionPolicy int
+
+const (
+	// NormalizeKeepFirst updates the value of the key in use, which keeps its
+	// spelling and position.
+	NormalizeKeepFirst NormalizeCollisionPolicy = iota
+	// NormalizeKeepLast updates the value of the key in use and replaces its
+	// spelling with the new one, keeping its position.
+	NormalizeKeepLast
+	// NormalizeError rejects the key: Set panics while SetKey returns an error
+	// matching ErrKeyCollision.
+	NormalizeError
+)
+
+// ErrKeyCollision is returned by SetKey, wrapped with both keys, when a key
+// collides with another one under the NormalizeError policy.
+var ErrKeyCollision = errors.New("orderedmap: key collision")
+
+// SetKeyNormalizer Treat keys with the same normalized form, such as
+// strings.ToLower for a case-insensitive map, as the same key in the methods
+// looking up or setting a single key: Get, GetErr, MustGet, GetOrElse,
+// GetOrElseSet, GetAny, RequireKeys, Set, SetKey, SetIfAbsent, SetComputed,
+// Mutate, Patch and Delete. Keys are stored with their original spelling and
+// policy decides which spelling Set keeps on a collision; SetIfAbsent leaves
+// a colliding key alone whatever the policy. Delete removes the colliding
+// entry whatever spelling is given, so setting the key again afterwards
+// appends it at the end. Other methods only match keys spelled as stored.
+// Finding a colliding key scans the keys. A nil fn removes the normalizer.
+func (o *OrderedMap[T]) SetKeyNormalizer(fn func(key string) string, policy NormalizeCollisionPolicy) {
+	o.mustBeMutable()
+	o.normalizer = fn
+	o.collisionPolicy = policy
+}
+
+// lookupKey returns the stored key matching key, or key itself when none does.
+func (o *OrderedMap[T]) lookupKey(key string) string {
+	if _, exists := o.values[key]; exists || o.normalizer == nil {
+		return key
+	}
+	n := o.normalizer(key)
+	for _, k := range o.keys {
+		if o.normalizer(k) == n {
+			return k
+		}
+	}
+	return key
+}
+
+// resolveKey returns the key Set should store value under, applying the
+// collision policy.
+func (o *OrderedMap[T]) resolveKey(key string) (string, error) {
+	stored := o.lookupKey(key)
+	if stored == key {
+		return key, nil
+	}
+	switch o.collisionPolicy {
+	case NormalizeKeepLast:
+		if err := o.validateKey(key); err != nil {
+			return "", err
+		}
+		for i, k := range o.keys {
+			if k == stored {
+				o.keys[i] = key
+				break
+			}
+		}
+		o.values[key] = o.values[stored]
+		delete(o.values, stored)
+		return key, nil
+	case NormalizeError:
+		return "", fmt.Errorf("%w: %q and %q", ErrKeyCollision, key, stored)
+	}
+	return stored, nil
+}
+
+func (o *OrderedMap[T]) validateKey(key string) error {
+	if o.validateUTF8 && !utf8.ValidString(key) {
+		return fmt.Errorf("orderedmap: key %q is not valid UTF-8", key)
+	}
+	if o.keyValidator == nil {
+		return nil
+	}
+	return o.keyValidator(key)
+}
+
+// mustBeValidKey panics with the validator error when key is rejected.
+func (o *OrderedMap[T]) mustBeValidKey(key string) {
+	if err := o.validateKey(key); err != nil {
+		panic(err)
+	}
 }
 
 func (o *OrderedMap[T]) Get(key string) (T, bool) {
-	val, exists := o.values[key]
+	val, exists := o.values[o.lookupKey(key)]
 	return val, exists
 }
 
+// ErrKeyNotFound is returned by GetErr, wrapped with the key, when the key is
+// not in use.
+var ErrKeyNotFound = errors.New("orderedmap: key not found")
+
+// GetErr Like Get, but a missing key is reported with an error matching
+// ErrKeyNotFound.
+func (o *OrderedMap[T]) GetErr(key string) (T, error) {
+	val, exists := o.Get(key)
+	if !exists {
+		return val, fmt.Errorf("%w: %q", ErrKeyNotFound, key)
+	}
+	return val, nil
+}
+
+// MustGet Like Get, but panics when the key is not in use.
+func (o *OrderedMap[T]) MustGet(key string) T {
+	val, err := o.GetErr(key)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// RequireKeys Return an error matching ErrKeyNotFound listing, in the order
+// given, the keys that are not in use, or nil when all of them are.
+func (o *OrderedMap[T]) RequireKeys(keys ...string) error {
+	var missing []string
+	reported := map[string]bool{}
+	for _, k := range keys {
+		if _, ok := o.Get(k); !ok && !reported[k] {
+			reported[k] = true
+			missing = append(missing, strconv.Quote(k))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrKeyNotFound, strings.Join(missing, ", "))
+}
+
+// GetOrElse Return the value of key, or the result of fn when key is not in
+// use. fn is only called on a miss and its result is not stored.
+func (o *OrderedMap[T]) GetOrElse(key string, fn func() T) T {
+	if val, exists := o.Get(key); exists {
+		return val
+	}
+	return fn()
+}
+
+// GetOrElseSet Like GetOrElse, but the result of fn is also stored under key.
+func (o *OrderedMap[T]) GetOrElseSet(key string, fn func() T) T {
+	if val, exists := o.Get(key); exists {
+		return val
+	}
+	o.mustBeMutable()
+	val := fn()
+	o.Set(key, val)
+	return val
+}
+
+// GetAny Return the first of keys in use, in argument order, along with its
+// value. It returns false when none of them is in use.
+func (o *OrderedMap[T]) GetAny(keys ...string) (string, T, bool) {
+	for _, k := range keys {
+		if val, exists := o.Get(k); exists {
+			return k, val, true
+		}
+	}
+	var zero T
+	return "", zero, false
+}
+
 func (o *OrderedMap[T]) Set(key string, value T) {
+	o.mustBeMutable()
+	key, err := o.resolveKey(key)
+	if err != nil {
+		panic(err)
+	}
 	_, exists := o.values[key]
 	if !exists {
+		o.mustBeValidKey(key)
+		o.initValues()
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// initValues allocates the values of a zero value map.
+func (o *OrderedMap[T]) initValues() {
+	if o.values == nil {
+		o.values = map[string]T{}
+	}
+}
+
+// SetKey Like Set, but returns an error instead of panicking when the map is
+// frozen or key is rejected by SetKeyValidator or SetValidateUTF8Keys.
+func (o *OrderedMap[T]) SetKey(key string, value T) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	key, err := o.resolveKey(key)
+	if err != nil {
+		return err
+	}
+	if _, exists := o.values[key]; !exists {
+		if err := o.validateKey(key); err != nil {
+			return err
+		}
+		o.initValues()
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+	return nil
+}
+
+// setLast sets the value of key and moves key to the end of the order.
+func (o *OrderedMap[T]) setLast(key string, value T) {
+	if _, exists := o.values[key]; exists {
+		for j, k := range o.keys {
+			if k == key {
+				copy(o.keys[j:], o.keys[j+1:])
+				break
+			}
+		}
+		o.keys[len(o.keys)-1] = key
+	} else {
+		o.initValues()
 		o.keys = append(o.keys, key)
 	}
 	o.values[key] = value
 }
 
+// SetIfAbsent Set the value only when key is not present yet. It reports
+// whether the value was inserted.
+func (o *OrderedMap[T]) SetIfAbsent(key string, value T) bool {
+	o.mustBeMutable()
+	if _, exists := o.Get(key); exists {
+		return false
+	}
+	o.mustBeValidKey(key)
+	o.initValues()
+	o.keys = append(o.keys, key)
+	o.values[key] = value
+	return true
+}
+
+// SetComputed Set key to the value fn computes from the map, appending key if
+// it is not in use. The map is frozen while fn runs, so fn modifying it panics
+// with ErrFrozen.
+func (o *OrderedMap[T]) SetComputed(key string, fn func(o *OrderedMap[T]) T) {
+	o.mustBeMutable()
+	value := func() T {
+		o.frozen = true
+		defer func() { o.frozen = false }()
+		return fn(o)
+	}()
+	o.Set(key, value)
+}
+
+// Mutate Set key to the value fn computes from its current value, or from the
+// zero value with existed false when key is not in use, in which case key is
+// appended.
+func (o *OrderedMap[T]) Mutate(key string, fn func(old T, existed bool) T) {
+	o.mustBeMutable()
+	old, existed := o.Get(key)
+	o.Set(key, fn(old, existed))
+}
+
+// Patch Set every entry of changes, in the order of changes. Existing keys
+// keep their position and new keys are appended. Unlike ApplyMergePatch,
+// values are replaced as a whole and nothing is deleted.
+func (o *OrderedMap[T]) Patch(changes *OrderedMap[T]) {
+	o.mustBeMutable()
+	for _, k := range changes.keys {
+		o.Set(k, changes.values[k])
+	}
+}
+
+// RenameKeys Rename, in place, the keys found in mapping to the name they
+// map to, keeping their position and value. Other keys are left unchanged, so
+// keys can be swapped. It returns an error, leaving the map unchanged, when
+// two keys would end up with the same name or a new name is rejected by
+// SetKeyValidator or SetValidateUTF8Keys.
+func (o *OrderedMap[T]) RenameKeys(mapping map[string]string) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	keys := make([]string, len(o.keys))
+	seen := make(map[string]string, len(o.keys))
+	for i, k := range o.keys {
+		name, ok := mapping[k]
+		if !ok {
+			name = k
+		} else if name != k {
+			if err := o.validateKey(name); err != nil {
+				return err
+			}
+		}
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("orderedmap: keys %q and %q are both renamed to %q", other, k, name)
+		}
+		seen[name] = k
+		keys[i] = name
+	}
+	values := make(map[string]T, len(o.values))
+	for i, k := range o.keys {
+		values[keys[i]] = o.values[k]
+	}
+	o.keys = keys
+	o.values = values
+	return nil
+}
+
+// Transaction Call fn with a copy of the map and, when fn returns nil, replace
+// the entries of the map with those of the copy. When fn returns an error or
+// panics the map is left untouched. Only entries are committed, not settings
+// changed on the copy. It returns ErrFrozen on a frozen map.
+// For an OrderedMap[interface{}] the copy is deep, as DeepClone makes it, so
+// changes to nested maps and slices are rolled back too and the nested values
+// committed are the copies. For other value types the copy is shallow: values
+// modified through pointers they hold are not rolled back.
+func (o *OrderedMap[T]) Transaction(fn func(tx *OrderedMap[T]) error) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	tx := o.clone()
+	if self, ok := any(o).(*OrderedMap[interface{}]); ok {
+		tx = any(deepCopyValue(self)).(*OrderedMap[T])
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	o.keys = tx.keys
+	o.values = tx.values
+	if self, ok := any(o).(*OrderedMap[interface{}]); ok {
+		// references the copy holds to itself must now lead to the map
+		replaceMapRefs(self, any(tx).(*OrderedMap[interface{}]), self, map[uintptr]bool{})
+	}
+	return nil
+}
+
 func (o *OrderedMap[T]) Delete(key string) {
+	o.mustBeMutable()
+	key = o.lookupKey(key)
 	// check key is in use
 	_, ok := o.values[key]
 	if !ok {
@@ -76,17 +548,426 @@ func (o *OrderedMap[T]) Delete(key string) {
 	delete(o.values, key)
 }
 
+// DeleteMulti Delete all the given keys in a single pass, keeping the order of
+// the remaining keys. Keys not in use are ignored. It returns the number of
+// keys removed.
+func (o *OrderedMap[T]) DeleteMulti(keys ...string) int {
+	o.mustBeMutable()
+	remove := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := o.values[k]; ok {
+			remove[k] = true
+		}
+	}
+	if len(remove) == 0 {
+		return 0
+	}
+	kept := o.keys[:0]
+	for _, k := range o.keys {
+		if remove[k] {
+			delete(o.values, k)
+		} else {
+			kept = append(kept, k)
+		}
+	}
+	o.keys = kept
+	return len(remove)
+}
+
+// Retain Delete every key not listed, keeping the order of the retained keys.
+// It returns the number of keys removed.
+func (o *OrderedMap[T]) Retain(keys ...string) int {
+	o.mustBeMutable()
+	keep := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		keep[k] = true
+	}
+	kept := o.keys[:0]
+	for _, k := range o.keys {
+		if keep[k] {
+			kept = append(kept, k)
+		} else {
+			delete(o.values, k)
+		}
+	}
+	removed := len(o.keys) - len(kept)
+	o.keys = kept
+	return removed
+}
+
+// DedupeValues Delete the entries whose value equals, according to eq, the
+// value of an earlier entry, keeping the first occurrence and the order of the
+// remaining keys. It returns the number of keys removed. See DedupeComparable
+// for comparable values.
+func (o *OrderedMap[T]) DedupeValues(eq func(a, b T) bool) int {
+	o.mustBeMutable()
+	kept := o.keys[:0]
+	for _, k := range o.keys {
+		duplicate := false
+		for _, other := range kept {
+			if eq(o.values[other], o.values[k]) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			delete(o.values, k)
+		} else {
+			kept = append(kept, k)
+		}
+	}
+	removed := len(o.keys) - len(kept)
+	o.keys = kept
+	return removed
+}
+
+// RemoveEmpty Delete every entry whose value satisfies isEmpty, keeping the
+// order of the others, and return the number removed. IsEmptyValue provides a
+// default notion of empty for interface{} values.
+func (o *OrderedMap[T]) RemoveEmpty(isEmpty func(T) bool) int {
+	o.mustBeMutable()
+	kept := o.keys[:0]
+	for _, k := range o.keys {
+		if isEmpty(o.values[k]) {
+			delete(o.values, k)
+		} else {
+			kept = append(kept, k)
+		}
+	}
+	removed := len(o.keys) - len(kept)
+	o.keys = kept
+	return removed
+}
+
+// PopBack Remove and return the last entry. It returns false when the map is
+// empty.
+func (o *OrderedMap[T]) PopBack() (string, T, bool) {
+	o.mustBeMutable()
+	var value T
+	if len(o.keys) == 0 {
+		return "", value, false
+	}
+	key := o.keys[len(o.keys)-1]
+	value = o.values[key]
+	o.keys = o.keys[:len(o.keys)-1]
+	delete(o.values, key)
+	return key, value, true
+}
+
+// Chunk Split the map into maps of at most n entries, in order. Only the last
+// one may be smaller. Chunk panics when n is not positive.
+func (o *OrderedMap[T]) Chunk(n int) []*OrderedMap[T] {
+	if n <= 0 {
+		panic(fmt.Sprintf("orderedmap: invalid chunk size %d", n))
+	}
+	chunks := make([]*OrderedMap[T], 0, (len(o.keys)+n-1)/n)
+	for start := 0; start < len(o.keys); start += n {
+		end := start + n
+		if end > len(o.keys) {
+			end = len(o.keys)
+		}
+		chunk := New[T]()
+		chunk.escapeHTML = o.escapeHTML
+		for _, k := range o.keys[start:end] {
+			chunk.keys = append(chunk.keys, k)
+			chunk.values[k] = o.values[k]
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// Page Return at most limit pairs in order, starting at offset. Out of range
+// offsets give an empty page.
+func (o *OrderedMap[T]) Page(offset, limit int) []Pair[T] {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(o.keys) || limit < 0 {
+		return []Pair[T]{}
+	}
+	if rest := len(o.keys) - offset; limit > rest {
+		limit = rest
+	}
+	page := make([]Pair[T], limit)
+	for i, k := range o.keys[offset : offset+limit] {
+		page[i] = Pair[T]{k, o.values[k]}
+	}
+	return page
+}
+
+// HeadTail Return, in order, the first n pairs and the last n pairs. When the
+// map has fewer than 2n entries, tail only holds those not already in head.
+func (o *OrderedMap[T]) HeadTail(n int) (head, tail []Pair[T]) {
+	if n < 0 {
+		n = 0
+	}
+	head = o.Page(0, n)
+	start := len(o.keys) - n
+	if start < len(head) {
+		start = len(head)
+	}
+	return head, o.Page(start, n)
+}
+
 func (o *OrderedMap[T]) Keys() []string {
+	if o.keys == nil {
+		return []string{}
+	}
 	return o.keys
 }
 
+// IsEmpty Report whether the map has no entries.
+func (o *OrderedMap[T]) IsEmpty() bool {
+	return len(o.keys) == 0
+}
+
+// ValuesMap Return the map holding the values, for fast lookups of many keys.
+// It is the map used internally, not a copy: it must not be modified. Use
+// ValuesMapCopy to get a map that can be.
+func (o *OrderedMap[T]) ValuesMap() map[string]T {
+	return o.values
+}
+
+// ValuesMapCopy Return a copy of the map holding the values.
+func (o *OrderedMap[T]) ValuesMapCopy() map[string]T {
+	values := make(map[string]T, len(o.values))
+	for k, v := range o.values {
+		values[k] = v
+	}
+	return values
+}
+
+// KeysWhere Return, in order, the keys whose value satisfies pred.
+func (o *OrderedMap[T]) KeysWhere(pred func(value T) bool) []string {
+	keys := []string{}
+	for _, k := range o.keys {
+		if pred(o.values[k]) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Find Return the first entry, in order, whose key and value satisfy pred.
+func (o *OrderedMap[T]) Find(pred func(key string, value T) bool) (string, T, bool) {
+	for _, k := range o.keys {
+		if v := o.values[k]; pred(k, v) {
+			return k, v, true
+		}
+	}
+	var zero T
+	return "", zero, false
+}
+
+// All Return whether every entry satisfies pred, true for an empty map.
+func (o *OrderedMap[T]) All(pred func(key string, value T) bool) bool {
+	for _, k := range o.keys {
+		if !pred(k, o.values[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Any Return whether at least one entry satisfies pred.
+func (o *OrderedMap[T]) Any(pred func(key string, value T) bool) bool {
+	_, _, ok := o.Find(pred)
+	return ok
+}
+
+// CountBy Count the entries per bucket named by keyFn. Buckets appear in the
+// order their first member was seen, as in GroupBy.
+func (o *OrderedMap[T]) CountBy(keyFn func(key string, v T) string) *OrderedMap[int] {
+	counts := New[int]()
+	counts.escapeHTML = o.escapeHTML
+	for _, k := range o.keys {
+		name := keyFn(k, o.values[k])
+		if _, ok := counts.values[name]; !ok {
+			counts.keys = append(counts.keys, name)
+		}
+		counts.values[name]++
+	}
+	return counts
+}
+
+// Run is a sequence of consecutive entries with equal values, as returned by
+// CompressRuns.
+type Run[T any] struct {
+	// Key is the key of the first entry of the run.
+	Key   string
+	Value T
+	Count int
+}
+
+// CompressRuns Return the runs of consecutive entries whose values are equal
+// according to eq, in order. Each run holds the value of its first entry.
+func (o *OrderedMap[T]) CompressRuns(eq func(a, b T) bool) []Run[T] {
+	runs := []Run[T]{}
+	for _, k := range o.keys {
+		v := o.values[k]
+		if last := len(runs) - 1; last >= 0 && eq(runs[last].Value, v) {
+			runs[last].Count++
+			continue
+		}
+		runs = append(runs, Run[T]{Key: k, Value: v, Count: 1})
+	}
+	return runs
+}
+
+// Unzip Return copies of the keys and of the values, aligned by index.
+func (o *OrderedMap[T]) Unzip() ([]string, []T) {
+	keys := make([]string, len(o.keys))
+	values := make([]T, len(o.keys))
+	for i, k := range o.keys {
+		keys[i] = k
+		values[i] = o.values[k]
+	}
+	return keys, values
+}
+
+// ToDocument Return one single-entry map per entry, in order, as used by
+// drivers representing ordered documents as a slice of maps.
+func (o *OrderedMap[T]) ToDocument() []map[string]T {
+	doc := make([]map[string]T, len(o.keys))
+	for i, k := range o.keys {
+		doc[i] = map[string]T{k: o.values[k]}
+	}
+	return doc
+}
+
+// EachIndexed Call fn for every entry in order with its position. Iteration
+// stops when fn returns false.
+func (o *OrderedMap[T]) EachIndexed(fn func(i int, key string, value T) bool) {
+	for i, k := range o.keys {
+		if !fn(i, k, o.values[k]) {
+			return
+		}
+	}
+}
+
+// indexOf returns the position of key in o.keys, -1 when key is not in use.
+func (o *OrderedMap[T]) indexOf(key string) int {
+	if _, ok := o.values[key]; !ok {
+		return -1
+	}
+	for i, k := range o.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// PrevKey Return the key before key. It returns false when key is the first
+// one or is not in use.
+func (o *OrderedMap[T]) PrevKey(key string) (string, bool) {
+	i := o.indexOf(key)
+	if i <= 0 {
+		return "", false
+	}
+	return o.keys[i-1], true
+}
+
+// NextKey Return the key after key. It returns false when key is the last one
+// or is not in use.
+func (o *OrderedMap[T]) NextKey(key string) (string, bool) {
+	i := o.indexOf(key)
+	if i < 0 || i == len(o.keys)-1 {
+		return "", false
+	}
+	return o.keys[i+1], true
+}
+
+// At Return the entry at index, counting from the end when index is negative:
+// At(-1) is the last entry. It returns false when index is out of range.
+func (o *OrderedMap[T]) At(index int) (string, T, bool) {
+	if index < 0 {
+		index += len(o.keys)
+	}
+	if index < 0 || index >= len(o.keys) {
+		var zero T
+		return "", zero, false
+	}
+	key := o.keys[index]
+	return key, o.values[key], true
+}
+
 // SortKeys Sort the map keys using your sort func
+// sortFunc is given a copy of the keys, which is only used when it still
+// holds every key exactly once; SortKeys panics otherwise, leaving the map
+// unchanged.
 func (o *OrderedMap[T]) SortKeys(sortFunc func(keys []string)) {
-	sortFunc(o.keys)
+	o.mustBeMutable()
+	keys := make([]string, len(o.keys))
+	copy(keys, o.keys)
+	sortFunc(keys)
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := o.values[k]; !ok || seen[k] {
+			panic(fmt.Sprintf("orderedmap: SortKeys sort func changed the keys: %q is unknown or repeated", k))
+		}
+		seen[k] = true
+	}
+	copy(o.keys, keys)
+}
+
+// SortKeysNumeric Sort the keys by numeric value, for objects keyed by
+// numbers such as "10", "2" and "1". Keys that are not finite numbers come
+// after the numeric ones, in lexical order; numerically equal keys such as
+// "1" and "1.0" are ordered lexically too.
+func (o *OrderedMap[T]) SortKeysNumeric() {
+	o.SortKeys(func(keys []string) {
+		numbers := make(map[string]float64, len(keys))
+		for _, k := range keys {
+			if f, err := strconv.ParseFloat(k, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				numbers[k] = f
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, aNumeric := numbers[keys[i]]
+			b, bNumeric := numbers[keys[j]]
+			switch {
+			case aNumeric != bNumeric:
+				return aNumeric
+			case aNumeric && a != b:
+				return a < b
+			}
+			return keys[i] < keys[j]
+		})
+	})
+}
+
+// ReorderByPreference Move the keys of preferred to the front, in that order,
+// the other keys following in their current order. Keys of preferred that are
+// not in use are ignored.
+func (o *OrderedMap[T]) ReorderByPreference(preferred []string) {
+	o.mustBeMutable()
+	copy(o.keys, o.preferredOrder(preferred))
+}
+
+// preferredOrder returns the keys of first that are in use, in that order,
+// followed by the other keys in map order.
+func (o OrderedMap[T]) preferredOrder(first []string) []string {
+	placed := make(map[string]bool, len(first))
+	keys := make([]string, 0, len(o.keys))
+	for _, k := range first {
+		if _, ok := o.values[k]; ok && !placed[k] {
+			placed[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range o.keys {
+		if !placed[k] {
+			keys = append(keys, k)
+		}
+	}
+	return keys
 }
 
 // Sort Sort the map using your sort func
 func (o *OrderedMap[T]) Sort(lessFunc func(a *Pair[T], b *Pair[T]) bool) {
+	o.mustBeMutable()
 	pairs := make([]*Pair[T], len(o.keys))
 	for i, key := range o.keys {
 		pairs[i] = &Pair[T]{key, o.values[key]}
@@ -99,24 +980,212 @@ func (o *OrderedMap[T]) Sort(lessFunc func(a *Pair[T], b *Pair[T]) bool) {
 	}
 }
 
+// Sorted Return a copy of the map sorted with lessFunc, leaving the map
+// unchanged. The copy has the same settings but is not frozen.
+func (o *OrderedMap[T]) Sorted(lessFunc func(a *Pair[T], b *Pair[T]) bool) *OrderedMap[T] {
+	sorted := o.clone()
+	sorted.Sort(lessFunc)
+	return sorted
+}
+
+// SortedRange Call fn for each entry in the order given by lessFunc until fn
+// returns false, leaving the map unchanged. The entries are sorted once, before
+// the first call.
+func (o *OrderedMap[T]) SortedRange(lessFunc func(a *Pair[T], b *Pair[T]) bool, fn func(key string, value T) bool) {
+	pairs := make([]*Pair[T], len(o.keys))
+	for i, key := range o.keys {
+		pairs[i] = &Pair[T]{key, o.values[key]}
+	}
+	sort.Sort(ByPair[T]{pairs, lessFunc})
+	for _, pair := range pairs {
+		if !fn(pair.key, pair.value) {
+			return
+		}
+	}
+}
+
+// Rotate Shift the keys k positions towards the front, the first k keys moving
+// to the back, so Rotate(1) turns a, b, c into b, c, a. A negative k rotates
+// towards the back; k is taken modulo the number of keys.
+func (o *OrderedMap[T]) Rotate(k int) {
+	o.mustBeMutable()
+	n := len(o.keys)
+	if n < 2 {
+		return
+	}
+	if k %= n; k < 0 {
+		k += n
+	}
+	rotated := make([]string, 0, n)
+	rotated = append(rotated, o.keys[k:]...)
+	rotated = append(rotated, o.keys[:k]...)
+	copy(o.keys, rotated)
+}
+
+// IsSorted Report whether the keys are already ordered according to less.
+func (o *OrderedMap[T]) IsSorted(less func(a, b string) bool) bool {
+	for i := 1; i < len(o.keys); i++ {
+		if less(o.keys[i], o.keys[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSortedByKey Report whether the keys are already in lexical order.
+func (o *OrderedMap[T]) IsSortedByKey() bool {
+	return sort.StringsAreSorted(o.keys)
+}
+
+// TopN Return the n greatest pairs according to less, greatest first, without
+// reordering the map. Pairs comparing equal keep their insertion order.
+// When n exceeds the map size every pair is returned.
+func (o *OrderedMap[T]) TopN(n int, less func(a *Pair[T], b *Pair[T]) bool) []*Pair[T] {
+	if n <= 0 {
+		return []*Pair[T]{}
+	}
+	h := &pairHeap[T]{less: less}
+	for i, key := range o.keys {
+		entry := rankedPair[T]{&Pair[T]{key, o.values[key]}, i}
+		if h.Len() < n {
+			heap.Push(h, entry)
+		} else if h.worse(h.entries[0], entry) {
+			h.entries[0] = entry
+			heap.Fix(h, 0)
+		}
+	}
+	pairs := make([]*Pair[T], h.Len())
+	for i := len(pairs) - 1; i >= 0; i-- {
+		pairs[i] = heap.Pop(h).(rankedPair[T]).pair
+	}
+	return pairs
+}
+
+type rankedPair[T any] struct {
+	pair  *Pair[T]
+	index int
+}
+
+// pairHeap is a min-heap keeping the worst ranked pair on top.
+type pairHeap[T any] struct {
+	entries []rankedPair[T]
+	less    func(a *Pair[T], b *Pair[T]) bool
+}
+
+// worse reports whether a ranks below b, later pairs ranking below earlier
+// ones on ties.
+func (h *pairHeap[T]) worse(a, b rankedPair[T]) bool {
+	if h.less(a.pair, b.pair) {
+		return true
+	}
+	if h.less(b.pair, a.pair) {
+		return false
+	}
+	return a.index > b.index
+}
+
+func (h *pairHeap[T]) Len() int           { return len(h.entries) }
+func (h *pairHeap[T]) Less(i, j int) bool { return h.worse(h.entries[i], h.entries[j]) }
+func (h *pairHeap[T]) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+func (h *pairHeap[T]) Push(x interface{}) { h.entries = append(h.entries, x.(rankedPair[T])) }
+func (h *pairHeap[T]) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
+
+// UnmarshalJSON Decode a JSON object, keeping the order of its keys.
+// For an OrderedMap[interface{}], nested objects are decoded as
+// *OrderedMap[interface{}], not map[string]interface{}, so that their order
+// is kept too; arrays are decoded as []interface{}.
+// A leading UTF-8 byte order mark is skipped; note that json.Unmarshal rejects
+// such input before calling UnmarshalJSON, so call it directly or use Scan.
+// A JSON null empties the map; a nil *OrderedMap decoded by json.Unmarshal
+// stays nil.
 func (o *OrderedMap[T]) UnmarshalJSON(b []byte) error {
-	if o.values == nil {
-		o.values = map[string]T{}
+	return o.unmarshalJSON(b, o.decodeConfig())
+}
+
+// DecodeOptions gathers the decoding settings of UnmarshalJSONTyped.
+type DecodeOptions struct {
+	// UseNumber decodes numbers as json.Number, as SetUseNumber does.
+	UseNumber bool
+	// IntegersAsInt64 decodes integers as int64, as DecodeIntegersAsInt64
+	// does.
+	IntegersAsInt64 bool
+	// DisallowDuplicateKeys makes a key repeated within an object an error
+	// instead of keeping its last value.
+	DisallowDuplicateKeys bool
+	// MaxDepth, when positive, limits the nesting of objects and arrays, the
+	// map itself being at depth 1.
+	MaxDepth int
+}
+
+// UnmarshalJSONTyped Like UnmarshalJSON, with the decoding settings of opts
+// instead of those of the map, which are left unchanged. Nested maps of an
+// OrderedMap[interface{}] are decoded with opts too; values of other types
+// are decoded by encoding/json, which ignores DisallowDuplicateKeys and
+// MaxDepth.
+func (o *OrderedMap[T]) UnmarshalJSONTyped(data []byte, opts DecodeOptions) error {
+	c := o.decodeConfig()
+	_, generic := any(o.values).(map[string]interface{})
+	c.useNumber = opts.UseNumber
+	c.intsAsInt64 = opts.IntegersAsInt64 && generic
+	c.disallowDuplicates = opts.DisallowDuplicateKeys
+	c.maxDepth = opts.MaxDepth
+	return o.unmarshalJSON(data, c)
+}
+
+func (o *OrderedMap[T]) unmarshalJSON(b []byte, c decodeConfig) error {
+	if o.frozen {
+		return ErrFrozen
 	}
-	err := json.Unmarshal(b, &o.values)
+	// tolerate a UTF-8 byte order mark, as written by some tools
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	dec := c.newDecoder(b)
+	token, err := dec.Token()
 	if err != nil {
 		return err
 	}
-	dec := json.NewDecoder(bytes.NewReader(b))
-	if _, err = dec.Token(); err != nil { // skip '{'
+	if token == nil {
+		// null empties the map, as it does a map of encoding/json
+		o.keys = []string{}
+		o.values = map[string]T{}
+		return expectEOF(dec)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("orderedmap: cannot unmarshal %v into an ordered map", token)
+	}
+	if c, err = c.enter(); err != nil {
 		return err
 	}
-	o.keys = make([]string, 0, len(o.values))
-	return decodeOrderedMap(dec, o)
+	o.keys = []string{}
+	o.values = map[string]T{}
+	if err = decodeOrderedMap(dec, o, c); err != nil {
+		return err
+	}
+	return expectEOF(dec)
+}
+
+// expectEOF fails when anything but white space follows the top-level value
+// read by dec, as json.Unmarshal does.
+func expectEOF(dec *json.Decoder) error {
+	_, err := dec.Token()
+	if err == io.EOF {
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("orderedmap: invalid data after top-level value")
+	}
+	return err
 }
 
-func decodeOrderedMap[T any](dec *json.Decoder, o *OrderedMap[T]) error {
-	hasKey := make(map[string]bool, len(o.values))
+// decodeOrderedMap reads the entries of an object whose opening '{' has
+// already been consumed. Keys are tracked in order here while each value is
+// decoded by encoding/json, so a T implementing json.Unmarshaler is honored.
+// Values of an OrderedMap[interface{}] go through decodeValue instead so that
+// nested objects keep their order too.
+func decodeOrderedMap[T any](dec *json.Decoder, o *OrderedMap[T], c decodeConfig) error {
 	for {
 		token, err := dec.Token()
 		if err != nil {
@@ -125,86 +1194,219 @@ func decodeOrderedMap[T any](dec *json.Decoder, o *OrderedMap[T]) error {
 		if delim, ok := token.(json.Delim); ok && delim == '}' {
 			return nil
 		}
-		key := token.(string)
-		if hasKey[key] {
-			// duplicate key
-			for j, k := range o.keys {
-				if k == key {
-					copy(o.keys[j:], o.keys[j+1:])
-					break
-				}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("orderedmap: unexpected token %v", token)
+		}
+		if _, exists := o.values[key]; exists && c.disallowDuplicates {
+			return fmt.Errorf("orderedmap: duplicate key %q", key)
+		} else if !exists {
+			if err = o.validateKey(key); err != nil {
+				return err
 			}
-			o.keys[len(o.keys)-1] = key
-		} else {
-			hasKey[key] = true
-			o.keys = append(o.keys, key)
 		}
 
-		token, err = dec.Token()
+		var value T
+		if p, ok := any(&value).(*interface{}); ok {
+			*p, err = decodeValue(dec, c)
+		} else {
+			err = dec.Decode(&value)
+		}
 		if err != nil {
 			return err
 		}
-		if delim, ok := token.(json.Delim); ok {
-			switch delim {
-			case '{':
-				if err = decodeOrderedMap(dec, &OrderedMap[T]{}); err != nil {
-					return err
-				}
-			case '[':
-				if err = decodeSlice(dec, []T{}, o.escapeHTML); err != nil {
-					return err
-				}
-			}
-		}
+		// a duplicate key takes the position of its last occurrence
+		o.setLast(key, value)
 	}
 }
 
-func decodeSlice[T any](dec *json.Decoder, s []T, escapeHTML bool) error {
-	for index := 0; ; index++ {
-		token, err := dec.Token()
-		if err != nil {
-			return err
+// decodeConfig holds the settings of a map that apply to the values it
+// decodes, including nested maps.
+type decodeConfig struct {
+	escapeHTML htmlEscaping
+	useNumber  bool
+	// intsAsInt64 is only set for maps of interface{} values, whose numbers
+	// are decoded by decodeValue.
+	intsAsInt64 bool
+	// disallowDuplicates, maxDepth and depth are set by UnmarshalJSONTyped
+	disallowDuplicates bool
+	maxDepth, depth    int
+}
+
+// enter returns the config to decode the content of an object or array,
+// failing when it is nested deeper than allowed.
+func (c decodeConfig) enter() (decodeConfig, error) {
+	c.depth++
+	if c.maxDepth > 0 && c.depth > c.maxDepth {
+		return c, fmt.Errorf("orderedmap: nesting exceeds the maximum depth of %d", c.maxDepth)
+	}
+	return c, nil
+}
+
+func (o *OrderedMap[T]) decodeConfig() decodeConfig {
+	_, generic := any(o.values).(map[string]interface{})
+	return decodeConfig{
+		escapeHTML:  o.escapeHTML,
+		useNumber:   o.useNumber,
+		intsAsInt64: o.intsAsInt64 && generic,
+	}
+}
+
+func (c decodeConfig) newDecoder(b []byte) *json.Decoder {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if c.useNumber || c.intsAsInt64 {
+		dec.UseNumber()
+	}
+	return dec
+}
+
+// number converts n as DecodeIntegersAsInt64 describes.
+func (c decodeConfig) number(n json.Number) (interface{}, error) {
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	if c.useNumber {
+		return n, nil
+	}
+	return n.Float64()
+}
+
+// decodeValue reads the next JSON value, decoding objects as
+// *OrderedMap[interface{}] with the settings of c and arrays as []interface{}.
+func decodeValue(dec *json.Decoder, c decodeConfig) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		if n, ok := token.(json.Number); ok && c.intsAsInt64 {
+			return c.number(n)
+		}
+		return token, nil
+	}
+	if c, err = c.enter(); err != nil {
+		return nil, err
+	}
+	switch delim {
+	case '{':
+		o := New[interface{}]()
+		o.escapeHTML = c.escapeHTML
+		o.useNumber = c.useNumber
+		o.intsAsInt64 = c.intsAsInt64
+		if err = decodeOrderedMap(dec, o, c); err != nil {
+			return nil, err
 		}
-		if delim, ok := token.(json.Delim); ok {
-			switch delim {
-			case '{':
-				if index < len(s) {
-					if err = decodeOrderedMap(dec, &OrderedMap[T]{}); err != nil {
-						return err
-					}
-				} else if err = decodeOrderedMap(dec, &OrderedMap[T]{}); err != nil {
-					return err
-				}
-			case '[':
-				if err = decodeSlice(dec, []T{}, escapeHTML); err != nil {
-					return err
-				}
-			case ']':
-				return nil
+		return o, nil
+	case '[':
+		s := []interface{}{}
+		for dec.More() {
+			v, err := decodeValue(dec, c)
+			if err != nil {
+				return nil, err
 			}
+			s = append(s, v)
+		}
+		if _, err = dec.Token(); err != nil { // skip ']'
+			return nil, err
 		}
+		return s, nil
 	}
+	return nil, fmt.Errorf("orderedmap: unexpected delimiter %v", delim)
 }
 
 func (o OrderedMap[T]) MarshalJSON() ([]byte, error) {
+	state := o.encodeState()
+	defer state.release()
+	if err := o.encodeJSON(&state.out, state); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), state.out.Bytes()...), nil
+}
+
+// MarshalIndent Like MarshalJSON but indented as json.MarshalIndent does,
+// while honoring SetEscapeHTML.
+func (o OrderedMap[T]) MarshalIndent(prefix, indent string) ([]byte, error) {
+	b, err := o.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
 	var buf bytes.Buffer
-	buf.WriteByte('{')
-	encoder := json.NewEncoder(&buf)
-	encoder.SetEscapeHTML(o.escapeHTML)
-	for i, k := range o.keys {
-		if i > 0 {
-			buf.WriteByte(',')
-		}
-		// add key
-		if err := encoder.Encode(k); err != nil {
-			return nil, err
+	if err = json.Indent(&buf, b, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GroupBy Group the entries into buckets keyed by keyFn. Buckets appear in the
+// order their first member was seen and keep the insertion order of members.
+func GroupBy[T any](o *OrderedMap[T], keyFn func(key string, v T) string) *OrderedMap[*OrderedMap[T]] {
+	groups := New[*OrderedMap[T]]()
+	groups.escapeHTML = o.escapeHTML
+	for _, k := range o.keys {
+		v := o.values[k]
+		name := keyFn(k, v)
+		group, ok := groups.Get(name)
+		if !ok {
+			group = New[T]()
+			group.escapeHTML = o.escapeHTML
+			groups.Set(name, group)
 		}
-		buf.WriteByte(':')
-		// add value
-		if err := encoder.Encode(o.values[k]); err != nil {
-			return nil, err
+		group.Set(k, v)
+	}
+	return groups
+}
+
+// Reduce Fold the entries in insertion order, starting from init.
+func Reduce[T, A any](o *OrderedMap[T], init A, fn func(acc A, key string, v T) A) A {
+	acc := init
+	for _, k := range o.keys {
+		acc = fn(acc, k, o.values[k])
+	}
+	return acc
+}
+
+// Zip Build a map from aligned keys and values, in slice order. A repeated
+// key keeps its last value and takes the position of its last occurrence,
+// as duplicate keys do in UnmarshalJSON. It returns an error when the slices
+// have different lengths.
+func Zip[T any](keys []string, values []T) (*OrderedMap[T], error) {
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("orderedmap: %d keys for %d values", len(keys), len(values))
+	}
+	o := New[T]()
+	for i, k := range keys {
+		o.setLast(k, values[i])
+	}
+	return o, nil
+}
+
+// DeepClone Return a copy of o where nested *OrderedMap[interface{}],
+// []interface{} and map[string]interface{} values are copied too, so changes
+// at any level do not affect o. Order and settings are kept at every level;
+// the copies are not frozen. Other values are copied as is.
+func DeepClone(o *OrderedMap[interface{}]) *OrderedMap[interface{}] {
+	if o == nil {
+		return nil
+	}
+	return deepCopyValue(o).(*OrderedMap[interface{}])
+}
+
+// DedupeComparable Like DedupeValues comparing values with ==, in a single
+// pass.
+func DedupeComparable[T comparable](o *OrderedMap[T]) int {
+	o.mustBeMutable()
+	seen := make(map[T]bool, len(o.keys))
+	kept := o.keys[:0]
+	for _, k := range o.keys {
+		if v := o.values[k]; seen[v] {
+			delete(o.values, k)
+		} else {
+			seen[v] = true
+			kept = append(kept, k)
 		}
 	}
-	buf.WriteByte('}')
-	return buf.Bytes(), nil
+	removed := len(o.keys) - len(kept)
+	o.keys = kept
+	return removed
 }
@@ -2,6 +2,7 @@ package orderedmap
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -249,6 +250,27 @@ func TestUnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestUnmarshalJSONNestedObjects(t *testing.T) {
+	o := New[interface{}]()
+	if err := json.Unmarshal([]byte(`{"a":{"z":1,"y":2},"b":[{"x":3}]}`), o); err != nil {
+		t.Fatal(err)
+	}
+	a, ok := o.MustGet("a").(*OrderedMap[interface{}])
+	if !ok {
+		t.Fatalf("nested object decoded as %T", o.MustGet("a"))
+	}
+	if k := strings.Join(a.Keys(), ","); k != "z,y" {
+		t.Error("nested object order", k)
+	}
+	b, ok := o.MustGet("b").([]interface{})
+	if !ok {
+		t.Fatalf("array decoded as %T", o.MustGet("b"))
+	}
+	if _, ok = b[0].(*OrderedMap[interface{}]); !ok {
+		t.Errorf("object in an array decoded as %T", b[0])
+	}
+}
+
 func TestUnmarshalJSONDuplicateKeys(t *testing.T) {
 	s := `{
 		"a": [{}, []],
@@ -358,6 +380,38 @@ func TestOrderedMap_SortKeys(t *testing.T) {
 	}
 }
 
+func TestOrderedMap_ReorderByPreference(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		o.Set(k, i)
+	}
+	o.ReorderByPreference([]string{"d", "unknown", "b", "d"})
+	if k := strings.Join(o.Keys(), " "); k != "d b a c e" {
+		t.Error("ReorderByPreference result is incorrect", k)
+	}
+	if v, _ := o.Get("d"); v != 3 {
+		t.Error("ReorderByPreference changed a value", v)
+	}
+	o.ReorderByPreference(nil)
+	if k := strings.Join(o.Keys(), " "); k != "d b a c e" {
+		t.Error("ReorderByPreference without preference", k)
+	}
+}
+
+func TestOrderedMap_SortKeysNumeric(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"10", "b", "2", "-1.5", "a", "1", "NaN", "1.0", "1e1x"} {
+		o.Set(k, i)
+	}
+	o.SortKeysNumeric()
+	if k := strings.Join(o.Keys(), " "); k != "-1.5 1 1.0 2 10 1e1x NaN a b" {
+		t.Error("SortKeysNumeric result is incorrect", k)
+	}
+	if v, _ := o.Get("10"); v != 0 {
+		t.Error("SortKeysNumeric changed a value", v)
+	}
+}
+
 func TestOrderedMap_Sort(t *testing.T) {
 	s := `
 {
@@ -386,6 +440,76 @@ func TestOrderedMap_Sort(t *testing.T) {
 	}
 }
 
+func TestOrderedMap_SortedRange(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("c", 3)
+	o.Set("a", 1)
+	var seen []string
+	o.SortedRange(func(a *Pair[int], b *Pair[int]) bool {
+		return a.value < b.value
+	}, func(key string, value int) bool {
+		seen = append(seen, key)
+		return value < 2
+	})
+	if strings.Join(seen, ",") != "a,b" {
+		t.Error("SortedRange order or early stop is incorrect", seen)
+	}
+	if k := o.Keys(); strings.Join(k, ",") != "b,c,a" {
+		t.Error("SortedRange modified the order", k)
+	}
+}
+
+func TestOrderedMap_Sorted(t *testing.T) {
+	o := New[int]()
+	o.SetEscapeHTML(false)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	o.Set("a", 1)
+	o.Freeze()
+	sorted := o.Sorted(func(a *Pair[int], b *Pair[int]) bool { return a.value < b.value })
+	if k := strings.Join(sorted.Keys(), " "); k != "a b c" {
+		t.Error("Sorted keys", k)
+	}
+	if k := strings.Join(o.Keys(), " "); k != "b c a" {
+		t.Error("Sorted changed the map", k)
+	}
+	if !sorted.escapeHTML.skipValues || sorted.IsFrozen() {
+		t.Error("Sorted settings")
+	}
+	sorted.Set("d", 4)
+	if _, ok := o.Get("d"); ok {
+		t.Error("Sorted copy shares values with the map")
+	}
+}
+
+func TestOrderedMap_Rotate(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"a", "b", "c", "d"} {
+		o.Set(k, i)
+	}
+	cases := []struct {
+		k    int
+		want string
+	}{
+		{1, "b c d a"},
+		{-1, "a b c d"},
+		{6, "c d a b"},
+		{-9, "b c d a"},
+		{0, "b c d a"},
+	}
+	for _, c := range cases {
+		o.Rotate(c.k)
+		if got := strings.Join(o.Keys(), " "); got != c.want {
+			t.Errorf("Rotate(%d) = %s, want %s", c.k, got, c.want)
+		}
+	}
+	if v, _ := o.Get("b"); v != 1 {
+		t.Error("Rotate changed a value", v)
+	}
+	New[int]().Rotate(3)
+}
+
 // https://github.com/iancoleman/orderedmap/issues/11
 func TestOrderedMap_empty_array(t *testing.T) {
 	srcStr := `{"x":[]}`
@@ -417,3 +541,1436 @@ func TestOrderedMap_empty_map(t *testing.T) {
 		t.Error("Got", marshalledStr)
 	}
 }
+
+type upperString string
+
+func (u *upperString) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*u = upperString(strings.ToUpper(s))
+	return nil
+}
+
+func TestUnmarshalJSONValueUnmarshaler(t *testing.T) {
+	s := `{"b":"x","a":"y","c":"z"}`
+	o := New[upperString]()
+	err := json.Unmarshal([]byte(s), &o)
+	if err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	expectedKeys := []string{"b", "a", "c"}
+	expectedValues := []upperString{"X", "Y", "Z"}
+	k := o.Keys()
+	if len(k) != len(expectedKeys) {
+		t.Fatal("Unmarshal key count", len(k), "!=", len(expectedKeys))
+	}
+	for i := range k {
+		if k[i] != expectedKeys[i] {
+			t.Error("Unmarshal root key order", i, k[i], "!=", expectedKeys[i])
+		}
+		v, _ := o.Get(k[i])
+		if v != expectedValues[i] {
+			t.Error("Unmarshal custom value", k[i], v, "!=", expectedValues[i])
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	o.Set("d", 4)
+	o.Set("c", 3)
+	o.Set("e", 5)
+	groups := GroupBy(o, func(key string, v int) string {
+		if v%2 == 0 {
+			return "even"
+		}
+		return "odd"
+	})
+	expectedGroups := []string{"even", "odd"}
+	expectedMembers := [][]string{{"b", "d"}, {"a", "c", "e"}}
+	k := groups.Keys()
+	if len(k) != len(expectedGroups) {
+		t.Fatal("GroupBy group count", len(k), "!=", len(expectedGroups))
+	}
+	for i := range k {
+		if k[i] != expectedGroups[i] {
+			t.Error("GroupBy group order", i, k[i], "!=", expectedGroups[i])
+		}
+		group, _ := groups.Get(k[i])
+		members := group.Keys()
+		if len(members) != len(expectedMembers[i]) {
+			t.Error("GroupBy member count", k[i], len(members), "!=", len(expectedMembers[i]))
+			continue
+		}
+		for j := range members {
+			if members[j] != expectedMembers[i][j] {
+				t.Error("GroupBy member order", k[i], j, members[j], "!=", expectedMembers[i][j])
+			}
+		}
+	}
+}
+
+func TestOrderedMap_Retain(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	o.Set("d", 4)
+	removed := o.Retain("d", "b", "missing")
+	if removed != 2 {
+		t.Error("Retain removed count", removed, "!= 2")
+	}
+	expectedKeys := []string{"b", "d"}
+	k := o.Keys()
+	if len(k) != len(expectedKeys) {
+		t.Fatal("Retain key count", len(k), "!=", len(expectedKeys))
+	}
+	for i := range k {
+		if k[i] != expectedKeys[i] {
+			t.Error("Retain key order", i, k[i], "!=", expectedKeys[i])
+		}
+	}
+	if _, ok := o.Get("a"); ok {
+		t.Error("Retain did not remove 'a' value")
+	}
+}
+
+func TestOrderedMap_DedupeValues(t *testing.T) {
+	build := func() *OrderedMap[string] {
+		o := New[string]()
+		for _, kv := range [][2]string{{"a", "x"}, {"b", "y"}, {"c", "X"}, {"d", "y"}, {"e", "z"}} {
+			o.Set(kv[0], kv[1])
+		}
+		return o
+	}
+	o := build()
+	if n := o.DedupeValues(strings.EqualFold); n != 2 {
+		t.Error("DedupeValues removed", n)
+	}
+	if k := strings.Join(o.Keys(), " "); k != "a b e" {
+		t.Error("DedupeValues keys", k)
+	}
+	if _, ok := o.Get("c"); ok {
+		t.Error("DedupeValues kept a removed value")
+	}
+
+	o = build()
+	if n := DedupeComparable(o); n != 1 {
+		t.Error("DedupeComparable removed", n)
+	}
+	if k := strings.Join(o.Keys(), " "); k != "a b c e" {
+		t.Error("DedupeComparable keys", k)
+	}
+}
+
+func TestOrderedMap_RemoveEmpty(t *testing.T) {
+	var nilMap *OrderedMap[int]
+	var nilPtr *int
+	full := New[int]()
+	full.Set("x", 1)
+	o := New[interface{}]()
+	o.Set("nil", nil)
+	o.Set("zero", 0)
+	o.Set("empty", "")
+	o.Set("s", "v")
+	o.Set("slice", []interface{}{})
+	o.Set("map", map[string]interface{}{})
+	o.Set("false", false)
+	o.Set("nilMap", nilMap)
+	o.Set("emptyMap", New[string]())
+	o.Set("full", full)
+	o.Set("nilPtr", nilPtr)
+	if n := o.RemoveEmpty(IsEmptyValue); n != 7 {
+		t.Error("RemoveEmpty removed", n)
+	}
+	if k := strings.Join(o.Keys(), " "); k != "zero s false full" {
+		t.Error("RemoveEmpty keys", k)
+	}
+
+	ints := New[int]()
+	ints.Set("a", 0)
+	ints.Set("b", 1)
+	if n := ints.RemoveEmpty(func(v int) bool { return v == 0 }); n != 1 || ints.Keys()[0] != "b" {
+		t.Error("RemoveEmpty on ints", n, ints.Keys())
+	}
+}
+
+func TestOrderedMap_SetIfAbsent(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	if o.SetIfAbsent("a", 2) {
+		t.Error("SetIfAbsent inserted an existing key")
+	}
+	if v, _ := o.Get("a"); v != 1 {
+		t.Error("SetIfAbsent overwrote existing value", v)
+	}
+	if !o.SetIfAbsent("b", 3) {
+		t.Error("SetIfAbsent did not insert a new key")
+	}
+	if v, _ := o.Get("b"); v != 3 {
+		t.Error("SetIfAbsent stored wrong value", v)
+	}
+	if len(o.Keys()) != 2 {
+		t.Error("SetIfAbsent key count", len(o.Keys()), "!= 2")
+	}
+}
+
+func TestOrderedMap_ZeroValue(t *testing.T) {
+	var o OrderedMap[int]
+	if !o.IsEmpty() || o.Keys() == nil || len(o.Keys()) != 0 {
+		t.Error("zero value is not empty", o.Keys())
+	}
+	if _, ok := o.Get("a"); ok {
+		t.Error("Get on a zero value")
+	}
+	o.Delete("a")
+	o.Set("b", 1)
+	o.SetIfAbsent("a", 2)
+	if o.IsEmpty() || strings.Join(o.Keys(), " ") != "b a" {
+		t.Error("Set on a zero value", o.Keys())
+	}
+	var s OrderedMap[string]
+	s.Set("<", "&")
+	if b, _ := s.MarshalJSON(); string(b) != `{"\u003c":"\u0026"}` {
+		t.Error("zero value does not escape HTML", string(b))
+	}
+	var u OrderedMap[int]
+	u.Mutate("n", func(old int, existed bool) int { return old + 1 })
+	if v, _ := u.Get("n"); v != 1 {
+		t.Error("Mutate on a zero value", v)
+	}
+}
+
+func TestOrderedMap_SetComputed(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	sum := func(m *OrderedMap[int]) int {
+		return Reduce(m, 0, func(acc int, key string, v int) int { return acc + v })
+	}
+	o.SetComputed("total", sum)
+	o.SetComputed("a", func(m *OrderedMap[int]) int { return m.MustGet("total") * 10 })
+	if k := strings.Join(o.Keys(), " "); k != "a b total" {
+		t.Error("SetComputed keys", k)
+	}
+	if v, _ := o.Get("total"); v != 3 {
+		t.Error("SetComputed value", v)
+	}
+	if v, _ := o.Get("a"); v != 30 {
+		t.Error("SetComputed existing key", v)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != ErrFrozen {
+				t.Error("SetComputed allowed fn to modify the map", r)
+			}
+		}()
+		o.SetComputed("c", func(m *OrderedMap[int]) int {
+			m.Set("d", 1)
+			return 0
+		})
+	}()
+	if o.IsFrozen() {
+		t.Error("SetComputed left the map frozen")
+	}
+	if _, ok := o.Get("d"); ok {
+		t.Error("SetComputed fn modified the map")
+	}
+}
+
+func TestOrderedMap_Mutate(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	inc := func(old int, existed bool) int {
+		if !existed && old != 0 {
+			t.Error("Mutate passed a non-zero value for a missing key", old)
+		}
+		return old + 1
+	}
+	o.Mutate("b", inc)
+	o.Mutate("a", inc)
+	o.Mutate("b", inc)
+	if k := strings.Join(o.Keys(), " "); k != "a b" {
+		t.Error("Mutate keys", k)
+	}
+	if a, b := o.MustGet("a"), o.MustGet("b"); a != 2 || b != 2 {
+		t.Error("Mutate values", a, b)
+	}
+	var existed []bool
+	o.Mutate("c", func(old int, ok bool) int {
+		existed = append(existed, ok)
+		return old
+	})
+	o.Mutate("c", func(old int, ok bool) int {
+		existed = append(existed, ok)
+		return old
+	})
+	if len(existed) != 2 || existed[0] || !existed[1] {
+		t.Error("Mutate existed flags", existed)
+	}
+}
+
+func TestOrderedMap_TopN(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 3)
+	o.Set("b", 7)
+	o.Set("c", 1)
+	o.Set("d", 7)
+	o.Set("e", 5)
+	less := func(a *Pair[int], b *Pair[int]) bool {
+		return a.value < b.value
+	}
+	top := o.TopN(3, less)
+	expectedKeys := []string{"b", "d", "e"}
+	if len(top) != len(expectedKeys) {
+		t.Fatal("TopN count", len(top), "!=", len(expectedKeys))
+	}
+	for i := range top {
+		if top[i].Key() != expectedKeys[i] {
+			t.Error("TopN order", i, top[i].Key(), "!=", expectedKeys[i])
+		}
+	}
+	all := o.TopN(10, less)
+	expectedKeys = []string{"b", "d", "e", "a", "c"}
+	if len(all) != len(expectedKeys) {
+		t.Fatal("TopN count", len(all), "!=", len(expectedKeys))
+	}
+	for i := range all {
+		if all[i].Key() != expectedKeys[i] {
+			t.Error("TopN order", i, all[i].Key(), "!=", expectedKeys[i])
+		}
+	}
+	// map order is untouched
+	expectedKeys = []string{"a", "b", "c", "d", "e"}
+	k := o.Keys()
+	for i := range k {
+		if k[i] != expectedKeys[i] {
+			t.Error("TopN changed key order", i, k[i], "!=", expectedKeys[i])
+		}
+	}
+}
+
+func TestMarshalIndentNoEscapeHTMLRecursive(t *testing.T) {
+	o := New[interface{}]()
+	o.SetEscapeHTML(false)
+	o.Set("x", "<&>")
+	nested := New[interface{}]()
+	nested.Set("y", "<>")
+	nested.Set("z", []interface{}{"&"})
+	o.Set("nested", nested)
+	expected := `{
+  "x": "<&>",
+  "nested": {
+    "y": "<>",
+    "z": [
+      "&"
+    ]
+  }
+}`
+	b, err := o.MarshalIndent("", "  ")
+	if err != nil {
+		t.Fatal("MarshalIndent error", err)
+	}
+	if string(b) != expected {
+		t.Error("MarshalIndent value is incorrect", string(b))
+	}
+
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(o); err != nil {
+		t.Fatal("Encode error", err)
+	}
+	if buf.String() != expected+"\n" {
+		t.Error("Encoder indented value is incorrect", buf.String())
+	}
+}
+
+func TestOrderedMap_DeleteMulti(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	o.Set("d", 4)
+	removed := o.DeleteMulti("c", "a", "missing", "a")
+	if removed != 2 {
+		t.Error("DeleteMulti removed count", removed, "!= 2")
+	}
+	expectedKeys := []string{"b", "d"}
+	k := o.Keys()
+	if len(k) != len(expectedKeys) {
+		t.Fatal("DeleteMulti key count", len(k), "!=", len(expectedKeys))
+	}
+	for i := range k {
+		if k[i] != expectedKeys[i] {
+			t.Error("DeleteMulti key order", i, k[i], "!=", expectedKeys[i])
+		}
+	}
+	if _, ok := o.Get("a"); ok {
+		t.Error("DeleteMulti did not remove 'a' value")
+	}
+}
+
+func TestOrderedMap_EachIndexed(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 10)
+	o.Set("b", 20)
+	o.Set("c", 30)
+	var visited []string
+	o.EachIndexed(func(i int, key string, value int) bool {
+		if value != (i+1)*10 {
+			t.Error("EachIndexed value", i, key, value)
+		}
+		visited = append(visited, fmt.Sprintf("%d %s", i, key))
+		return key != "b"
+	})
+	expected := []string{"0 a", "1 b"}
+	if len(visited) != len(expected) {
+		t.Fatal("EachIndexed did not stop early", visited)
+	}
+	for i := range visited {
+		if visited[i] != expected[i] {
+			t.Error("EachIndexed order", i, visited[i], "!=", expected[i])
+		}
+	}
+}
+
+func TestOrderedMap_Freeze(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	if o.IsFrozen() {
+		t.Error("New map is frozen")
+	}
+	o.Freeze()
+	if !o.IsFrozen() {
+		t.Error("Freeze did not freeze the map")
+	}
+	mutations := map[string]func(){
+		"Set":         func() { o.Set("c", 3) },
+		"SetIfAbsent": func() { o.SetIfAbsent("c", 3) },
+		"Delete":      func() { o.Delete("a") },
+		"DeleteMulti": func() { o.DeleteMulti("a") },
+		"Retain":      func() { o.Retain("a") },
+		"SortKeys":    func() { o.SortKeys(sort.Strings) },
+		"Sort":        func() { o.Sort(func(a *Pair[int], b *Pair[int]) bool { return a.value > b.value }) },
+	}
+	for name, mutate := range mutations {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrFrozen {
+					t.Error(name, "on a frozen map did not panic with ErrFrozen", r)
+				}
+			}()
+			mutate()
+		}()
+	}
+	if err := json.Unmarshal([]byte(`{"c":3}`), o); err == nil {
+		t.Error("Unmarshal into a frozen map did not fail")
+	}
+	if err := o.ApplyMergePatch([]byte(`{"c":3}`)); err != ErrFrozen {
+		t.Error("ApplyMergePatch on a frozen map", err)
+	}
+	// reads keep working
+	if v, ok := o.Get("a"); !ok || v != 1 {
+		t.Error("Get on a frozen map", v, ok)
+	}
+	if k := o.Keys(); len(k) != 2 || k[0] != "a" || k[1] != "b" {
+		t.Error("Frozen map was modified", k)
+	}
+}
+
+func TestOrderedMap_SetKeyValidator(t *testing.T) {
+	errBadKey := fmt.Errorf("bad key")
+	o := New[int]()
+	o.Set("Upper", 1)
+	o.SetKeyValidator(func(key string) error {
+		if key == "" || strings.ToLower(key) != key {
+			return errBadKey
+		}
+		return nil
+	})
+	// existing keys can still be updated
+	o.Set("Upper", 2)
+	o.Set("lower", 3)
+	func() {
+		defer func() {
+			if r := recover(); r != errBadKey {
+				t.Error("Set with an invalid key did not panic with the validator error", r)
+			}
+		}()
+		o.Set("Invalid", 4)
+	}()
+	if _, ok := o.Get("Invalid"); ok {
+		t.Error("Set stored an invalid key")
+	}
+	if err := json.Unmarshal([]byte(`{"ok":1,"NotOk":2}`), o); err == nil {
+		t.Error("Unmarshal accepted an invalid key")
+	}
+	if err := o.ApplyMergePatch([]byte(`{"Bad":1}`)); err != errBadKey {
+		t.Error("ApplyMergePatch accepted an invalid key", err)
+	}
+	if err := o.ApplyPatch([]byte(`[{"op":"add","path":"/Bad","value":1}]`)); err != errBadKey {
+		t.Error("ApplyPatch accepted an invalid key", err)
+	}
+	o.SetKeyValidator(nil)
+	o.Set("Invalid", 4)
+	if _, ok := o.Get("Invalid"); !ok {
+		t.Error("Removing the validator did not allow the key")
+	}
+}
+
+func TestOrderedMap_SetValidateUTF8Keys(t *testing.T) {
+	o := New[int]()
+	if err := o.SetKey("\xff", 1); err != nil {
+		t.Error("SetKey rejected a key by default", err)
+	}
+	o.SetValidateUTF8Keys(true)
+	if err := o.SetKey("\xfe", 2); err == nil {
+		t.Error("SetKey accepted invalid UTF-8")
+	}
+	if err := o.SetKey("\xff", 3); err != nil {
+		t.Error("SetKey rejected an existing key", err)
+	}
+	if err := o.SetKey("\u00e9", 4); err != nil {
+		t.Error("SetKey rejected valid UTF-8", err)
+	}
+	if k := o.Keys(); len(k) != 2 || o.MustGet("\xff") != 3 {
+		t.Error("SetKey keys", k)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Set accepted invalid UTF-8")
+			}
+		}()
+		o.Set("a\x80", 5)
+	}()
+	// encoding/json replaces invalid UTF-8 in keys
+	if err := o.UnmarshalJSON([]byte("{\"\xff\":1}")); err != nil {
+		t.Error("UnmarshalJSON error", err)
+	}
+	o.Freeze()
+	if err := o.SetKey("b", 1); err != ErrFrozen {
+		t.Error("SetKey on a frozen map", err)
+	}
+}
+
+func TestOrderedMap_SetKeyNormalizer(t *testing.T) {
+	o := New[int]()
+	o.SetKeyNormalizer(strings.ToLower, NormalizeKeepFirst)
+	o.Set("Name", 1)
+	o.Set("age", 2)
+	o.Set("NAME", 3)
+	if k := o.Keys(); strings.Join(k, ",") != "Name,age" || o.values["Name"] != 3 {
+		t.Error("NormalizeKeepFirst", k, o.values)
+	}
+	if v, ok := o.Get("nAmE"); !ok || v != 3 {
+		t.Error("Get with a normalizer", v, ok)
+	}
+
+	o.SetKeyNormalizer(strings.ToLower, NormalizeKeepLast)
+	o.Set("NAME", 4)
+	if k := o.Keys(); strings.Join(k, ",") != "NAME,age" || o.values["NAME"] != 4 {
+		t.Error("NormalizeKeepLast", k, o.values)
+	}
+
+	o.SetKeyNormalizer(strings.ToLower, NormalizeError)
+	if err := o.SetKey("Age", 5); !errors.Is(err, ErrKeyCollision) {
+		t.Error("NormalizeError SetKey", err)
+	}
+	if err := o.SetKey("age", 5); err != nil {
+		t.Error("NormalizeError rejected the stored key", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Set accepted a colliding key")
+			}
+		}()
+		o.Set("name", 6)
+	}()
+
+	o.Delete("name")
+	o.Set("Name", 7)
+	if k := o.Keys(); strings.Join(k, ",") != "age,Name" {
+		t.Error("Set after Delete", k)
+	}
+}
+
+func TestOrderedMap_SetKeyNormalizerLookups(t *testing.T) {
+	o := New[int]()
+	o.SetKeyNormalizer(strings.ToLower, NormalizeKeepFirst)
+	o.Set("a", 5)
+	o.Mutate("A", func(old int, existed bool) int {
+		if !existed {
+			t.Error("Mutate did not find the normalized key")
+		}
+		return old + 1
+	})
+	if v, _ := o.Get("a"); v != 6 {
+		t.Error("Mutate with a normalizer", v)
+	}
+	if o.SetIfAbsent("A", 9) {
+		t.Error("SetIfAbsent inserted a colliding key")
+	}
+	if v := o.GetOrElseSet("A", func() int { return 9 }); v != 6 {
+		t.Error("GetOrElseSet with a normalizer", v)
+	}
+	if v := o.GetOrElse("A", func() int { return 9 }); v != 6 {
+		t.Error("GetOrElse with a normalizer", v)
+	}
+	if v, err := o.GetErr("A"); err != nil || v != 6 || o.MustGet("A") != 6 {
+		t.Error("GetErr with a normalizer", v, err)
+	}
+	if err := o.RequireKeys("A"); err != nil {
+		t.Error("RequireKeys with a normalizer", err)
+	}
+	if k := o.Keys(); len(k) != 1 || k[0] != "a" {
+		t.Error("Normalized keys", k)
+	}
+}
+
+func TestOrderedMap_PrevNextKey(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	if k, ok := o.PrevKey("b"); !ok || k != "a" {
+		t.Error("PrevKey of b", k, ok)
+	}
+	if k, ok := o.NextKey("b"); !ok || k != "c" {
+		t.Error("NextKey of b", k, ok)
+	}
+	if _, ok := o.PrevKey("a"); ok {
+		t.Error("PrevKey of the first key")
+	}
+	if _, ok := o.NextKey("c"); ok {
+		t.Error("NextKey of the last key")
+	}
+	if _, ok := o.PrevKey("missing"); ok {
+		t.Error("PrevKey of a missing key")
+	}
+	if _, ok := o.NextKey("missing"); ok {
+		t.Error("NextKey of a missing key")
+	}
+}
+
+func TestOrderedMap_At(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	cases := []struct {
+		index int
+		key   string
+		value int
+		ok    bool
+	}{
+		{0, "a", 1, true},
+		{2, "c", 3, true},
+		{-1, "c", 3, true},
+		{-3, "a", 1, true},
+		{3, "", 0, false},
+		{-4, "", 0, false},
+	}
+	for _, c := range cases {
+		if k, v, ok := o.At(c.index); k != c.key || v != c.value || ok != c.ok {
+			t.Errorf("At(%d) = %q, %d, %v", c.index, k, v, ok)
+		}
+	}
+}
+
+func TestUnmarshalJSONUseNumber(t *testing.T) {
+	s := `{"n":123456789012345678,"f":1.50,"nested":{"id":98765432109876543210,"list":[1e3]}}`
+	o := New[interface{}]()
+	o.SetUseNumber(true)
+	if err := json.Unmarshal([]byte(s), o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	n, _ := o.Get("n")
+	if n != json.Number("123456789012345678") {
+		t.Errorf("UseNumber value: %#v", n)
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal("Marshalling json", err)
+	}
+	if string(b) != s {
+		t.Error("UseNumber round trip is incorrect", string(b))
+	}
+
+	o = New[interface{}]()
+	if err = json.Unmarshal([]byte(`{"n":123456789012345678}`), o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if n, _ = o.Get("n"); n != float64(123456789012345678) {
+		t.Errorf("Default number value: %#v", n)
+	}
+}
+
+func TestUnmarshalJSONNull(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	if err := json.Unmarshal([]byte("null"), o); err != nil {
+		t.Fatal("Unmarshal null error", err)
+	}
+	if !o.IsEmpty() {
+		t.Error("Unmarshal null did not empty the map", o.Keys())
+	}
+	o.Set("b", 2)
+	if err := o.UnmarshalJSON([]byte(" null ")); err != nil || !o.IsEmpty() {
+		t.Error("UnmarshalJSON null", err, o.Keys())
+	}
+
+	var p *OrderedMap[int]
+	if err := json.Unmarshal([]byte("null"), &p); err != nil || p != nil {
+		t.Error("Unmarshal null into a nil pointer", err, p)
+	}
+	var s struct {
+		M *OrderedMap[int] `json:"m"`
+	}
+	if err := json.Unmarshal([]byte(`{"m":null}`), &s); err != nil || s.M != nil {
+		t.Error("Unmarshal null field", err, s.M)
+	}
+	nested := New[interface{}]()
+	if err := json.Unmarshal([]byte(`{"m":null}`), nested); err != nil {
+		t.Fatal("Unmarshal nested null error", err)
+	}
+	if v, ok := nested.Get("m"); !ok || v != nil {
+		t.Error("nested null", v, ok)
+	}
+}
+
+func TestUnmarshalJSONTrailingData(t *testing.T) {
+	for _, s := range []string{`{"a":1} garbage`, `{"a":1}{"b":2}`, `null x`, `{"a":1}]`} {
+		o := New[int]()
+		if err := o.UnmarshalJSON([]byte(s)); err == nil {
+			t.Errorf("UnmarshalJSON accepted %q", s)
+		}
+	}
+	o := New[int]()
+	if err := o.Scan(`{"a":1}{"b":2}`); err == nil {
+		t.Error("Scan accepted two objects")
+	}
+	if err := o.UnmarshalJSON([]byte("{\"a\":1}\n\t ")); err != nil {
+		t.Error("UnmarshalJSON rejected trailing white space", err)
+	}
+}
+
+func TestUnmarshalJSONIntegersAsInt64(t *testing.T) {
+	s := `{"n":123456789012345678,"f":1.5,"nested":{"list":[7,1e3,98765432109876543210]}}`
+	o := New[interface{}]()
+	o.DecodeIntegersAsInt64()
+	if err := json.Unmarshal([]byte(s), o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if n, _ := o.Get("n"); n != int64(123456789012345678) {
+		t.Errorf("integer value: %#v", n)
+	}
+	if f, _ := o.Get("f"); f != 1.5 {
+		t.Errorf("float value: %#v", f)
+	}
+	nested, _ := o.Get("nested")
+	list, _ := nested.(*OrderedMap[interface{}]).Get("list")
+	l := list.([]interface{})
+	if l[0] != int64(7) || l[1] != float64(1000) || l[2] != float64(98765432109876543210) {
+		t.Errorf("nested values: %#v", l)
+	}
+
+	o = New[interface{}]()
+	o.DecodeIntegersAsInt64()
+	o.SetUseNumber(true)
+	if err := json.Unmarshal([]byte(`{"i":1,"f":1.50}`), o); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if i, _ := o.Get("i"); i != int64(1) {
+		t.Errorf("integer value with UseNumber: %#v", i)
+	}
+	if f, _ := o.Get("f"); f != json.Number("1.50") {
+		t.Errorf("float value with UseNumber: %#v", f)
+	}
+
+	typed := New[[]interface{}]()
+	typed.DecodeIntegersAsInt64()
+	if err := json.Unmarshal([]byte(`{"a":[1]}`), typed); err != nil {
+		t.Fatal("JSON Unmarshal error", err)
+	}
+	if a, _ := typed.Get("a"); a[0] != float64(1) {
+		t.Errorf("typed value: %#v", a[0])
+	}
+}
+
+func TestOrderedMap_UnmarshalJSONTyped(t *testing.T) {
+	o := New[interface{}]()
+	err := o.UnmarshalJSONTyped([]byte(`{"i":1,"f":1.5,"n":{"big":12345678901234567890}}`), DecodeOptions{UseNumber: true, IntegersAsInt64: true})
+	if err != nil {
+		t.Fatal("UnmarshalJSONTyped error", err)
+	}
+	if i, _ := o.Get("i"); i != int64(1) {
+		t.Errorf("integer value: %#v", i)
+	}
+	if f, _ := o.Get("f"); f != json.Number("1.5") {
+		t.Errorf("float value: %#v", f)
+	}
+	n, _ := o.Get("n")
+	if big, _ := n.(*OrderedMap[interface{}]).Get("big"); big != json.Number("12345678901234567890") {
+		t.Errorf("nested value: %#v", big)
+	}
+	if o.useNumber || o.intsAsInt64 {
+		t.Error("UnmarshalJSONTyped changed the map settings")
+	}
+
+	if err = o.UnmarshalJSONTyped([]byte(`{"a":1,"a":2}`), DecodeOptions{}); err != nil {
+		t.Error("UnmarshalJSONTyped rejected duplicates by default", err)
+	}
+	dup := DecodeOptions{DisallowDuplicateKeys: true}
+	for _, s := range []string{`{"a":1,"a":2}`, `{"a":{"b":1,"b":1}}`, `{"a":[{"b":1,"b":1}]}`} {
+		if err = o.UnmarshalJSONTyped([]byte(s), dup); err == nil {
+			t.Errorf("UnmarshalJSONTyped accepted duplicates in %s", s)
+		}
+	}
+	if err = o.UnmarshalJSONTyped([]byte(`{"a":{"a":1},"b":{"a":1}}`), dup); err != nil {
+		t.Error("UnmarshalJSONTyped rejected keys repeated in different objects", err)
+	}
+
+	depth := DecodeOptions{MaxDepth: 3}
+	if err = o.UnmarshalJSONTyped([]byte(`{"a":[{"b":1}],"c":{"d":[]}}`), depth); err != nil {
+		t.Error("UnmarshalJSONTyped rejected allowed depth", err)
+	}
+	for _, s := range []string{`{"a":[{"b":[]}]}`, `{"a":{"b":{"c":{}}}}`} {
+		if err = o.UnmarshalJSONTyped([]byte(s), depth); err == nil {
+			t.Errorf("UnmarshalJSONTyped accepted %s", s)
+		}
+	}
+	if err = o.UnmarshalJSONTyped([]byte(`{}`), DecodeOptions{MaxDepth: 1}); err != nil {
+		t.Error("UnmarshalJSONTyped rejected the root object", err)
+	}
+
+	typed := New[int]()
+	if err = typed.UnmarshalJSONTyped([]byte(`{"a":1,"a":2}`), dup); err == nil {
+		t.Error("UnmarshalJSONTyped accepted duplicates in a typed map")
+	}
+}
+
+func TestReduce(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	o.Set("c", 3)
+	sum := Reduce(o, 0, func(acc int, key string, v int) int {
+		return acc + v
+	})
+	if sum != 6 {
+		t.Error("Reduce sum", sum, "!= 6")
+	}
+	joined := Reduce(o, "", func(acc string, key string, v int) string {
+		return acc + key
+	})
+	if joined != "bac" {
+		t.Error("Reduce order", joined, "!= bac")
+	}
+}
+
+func TestOrderedMap_IsSorted(t *testing.T) {
+	o := New[int]()
+	if !o.IsSortedByKey() {
+		t.Error("Empty map is not sorted")
+	}
+	o.Set("a", 1)
+	o.Set("c", 3)
+	o.Set("b", 2)
+	if o.IsSortedByKey() {
+		t.Error("IsSortedByKey on unsorted keys")
+	}
+	o.SortKeys(sort.Strings)
+	if !o.IsSortedByKey() {
+		t.Error("IsSortedByKey on sorted keys")
+	}
+	desc := func(a, b string) bool { return a > b }
+	if o.IsSorted(desc) {
+		t.Error("IsSorted descending on ascending keys")
+	}
+	o.SortKeys(func(keys []string) { sort.Sort(sort.Reverse(sort.StringSlice(keys))) })
+	if !o.IsSorted(desc) {
+		t.Error("IsSorted descending on descending keys")
+	}
+}
+
+func TestOrderedMap_Patch(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	changes := New[int]()
+	changes.Set("e", 5)
+	changes.Set("b", 20)
+	changes.Set("d", 4)
+	o.Patch(changes)
+	b, _ := json.Marshal(o)
+	if string(b) != `{"a":1,"b":20,"c":3,"e":5,"d":4}` {
+		t.Error("Patch result is incorrect", string(b))
+	}
+}
+
+func TestOrderedMap_RenameKeys(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 3)
+	if err := o.RenameKeys(map[string]string{"a": "b", "b": "a", "c": "z", "missing": "m"}); err != nil {
+		t.Fatal(err)
+	}
+	if k := o.Keys(); strings.Join(k, ",") != "b,a,z" || o.MustGet("b") != 1 || o.MustGet("z") != 3 {
+		t.Error("RenameKeys is incorrect", k, o.values)
+	}
+	if err := o.RenameKeys(map[string]string{"b": "z"}); err == nil {
+		t.Error("RenameKeys accepted a rename to an existing key")
+	}
+	if err := o.RenameKeys(map[string]string{"a": "x", "b": "x"}); err == nil {
+		t.Error("RenameKeys accepted two keys renamed alike")
+	}
+	if k := o.Keys(); strings.Join(k, ",") != "b,a,z" {
+		t.Error("RenameKeys modified the map on error", k)
+	}
+}
+
+func TestOrderedMap_Transaction(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	failure := errors.New("failure")
+	err := o.Transaction(func(tx *OrderedMap[int]) error {
+		tx.Set("c", 3)
+		tx.Delete("a")
+		return failure
+	})
+	if err != failure {
+		t.Error("Transaction error", err)
+	}
+	if k := strings.Join(o.Keys(), " "); k != "a b" {
+		t.Error("failed Transaction changed the keys", k)
+	}
+	if _, ok := o.Get("c"); ok {
+		t.Error("failed Transaction changed the values")
+	}
+
+	err = o.Transaction(func(tx *OrderedMap[int]) error {
+		tx.Set("c", 3)
+		tx.Delete("a")
+		return nil
+	})
+	if err != nil {
+		t.Error("Transaction error", err)
+	}
+	if k := strings.Join(o.Keys(), " "); k != "b c" {
+		t.Error("Transaction keys", k)
+	}
+	if v, _ := o.Get("c"); v != 3 {
+		t.Error("Transaction value", v)
+	}
+
+	o.Freeze()
+	if err = o.Transaction(func(*OrderedMap[int]) error { return nil }); err != ErrFrozen {
+		t.Error("Transaction on a frozen map", err)
+	}
+}
+
+func TestOrderedMap_TransactionNested(t *testing.T) {
+	o := New[interface{}]()
+	if err := json.Unmarshal([]byte(`{"n":{"x":1},"s":["a"]}`), o); err != nil {
+		t.Fatal(err)
+	}
+	err := o.Transaction(func(tx *OrderedMap[interface{}]) error {
+		if err := tx.ApplyMergePatch([]byte(`{"n":{"x":2}}`)); err != nil {
+			return err
+		}
+		n, _ := tx.Get("n")
+		n.(*OrderedMap[interface{}]).Set("y", 3)
+		tx.MapStrings(strings.ToUpper)
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("Transaction did not return the error")
+	}
+	if b, _ := o.MarshalJSON(); string(b) != `{"n":{"x":1},"s":["a"]}` {
+		t.Error("Transaction kept nested changes on rollback", string(b))
+	}
+	if err = o.Transaction(func(tx *OrderedMap[interface{}]) error {
+		tx.MapStrings(strings.ToUpper)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := o.MarshalJSON(); string(b) != `{"n":{"x":1},"s":["A"]}` {
+		t.Error("Transaction did not commit nested changes", string(b))
+	}
+
+	// other value types are copied shallowly
+	p := New[*int]()
+	v := 1
+	p.Set("v", &v)
+	_ = p.Transaction(func(tx *OrderedMap[*int]) error {
+		*tx.MustGet("v") = 2
+		return errors.New("abort")
+	})
+	if v != 2 {
+		t.Error("Transaction copied a pointer value")
+	}
+}
+
+func TestOrderedMap_TransactionCycle(t *testing.T) {
+	o := New[interface{}]()
+	inner := New[interface{}]()
+	inner.Set("x", 1)
+	inner.Set("self", inner)
+	o.Set("self", o)
+	o.Set("a", inner)
+	o.Set("b", inner)
+	err := o.Transaction(func(tx *OrderedMap[interface{}]) error {
+		a := tx.MustGet("a").(*OrderedMap[interface{}])
+		if a == inner || a.MustGet("self") != a || tx.MustGet("b") != a {
+			t.Error("Transaction copy does not keep the shared structure")
+		}
+		if tx.MustGet("self") == o {
+			t.Error("Transaction copy refers to the original map")
+		}
+		a.Set("x", 2)
+		return errors.New("abort")
+	})
+	if err == nil || inner.MustGet("x") != 1 {
+		t.Error("Transaction rollback with cycles", err)
+	}
+	if err = o.Transaction(func(tx *OrderedMap[interface{}]) error {
+		tx.MustGet("a").(*OrderedMap[interface{}]).Set("x", 3)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if o.MustGet("self") != o {
+		t.Error("Transaction commit left a reference to the copy")
+	}
+	a := o.MustGet("a").(*OrderedMap[interface{}])
+	if a.MustGet("x") != 3 || a.MustGet("self") != a || o.MustGet("b") != a {
+		t.Error("Transaction commit with cycles")
+	}
+	if c := deepCopyValue([]interface{}{}); c == nil || len(c.([]interface{})) != 0 {
+		t.Error("deepCopyValue of an empty slice", c)
+	}
+}
+
+func TestOrderedMap_PopBack(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	k, v, ok := o.PopBack()
+	if !ok || k != "b" || v != 2 {
+		t.Error("PopBack returned", k, v, ok)
+	}
+	if _, exists := o.Get("b"); exists || len(o.Keys()) != 1 {
+		t.Error("PopBack did not remove the entry", o.Keys())
+	}
+	o.PopBack()
+	if k, v, ok = o.PopBack(); ok || k != "" || v != 0 {
+		t.Error("PopBack on an empty map returned", k, v, ok)
+	}
+}
+
+func TestOrderedMap_Chunk(t *testing.T) {
+	o := New[int]()
+	o.SetEscapeHTML(false)
+	for i, k := range []string{"e", "d", "c", "b", "a"} {
+		o.Set(k, i)
+	}
+	chunks := o.Chunk(2)
+	if len(chunks) != 3 {
+		t.Fatal("Chunk count", len(chunks))
+	}
+	var got []string
+	for _, c := range chunks {
+		b, _ := c.MarshalJSON()
+		got = append(got, string(b))
+		if !c.escapeHTML.skipKeys || !c.escapeHTML.skipValues {
+			t.Error("Chunk did not keep escapeHTML")
+		}
+	}
+	if s := strings.Join(got, " "); s != `{"e":0,"d":1} {"c":2,"b":3} {"a":4}` {
+		t.Error("Chunk result is incorrect", s)
+	}
+	if chunks = New[int]().Chunk(3); len(chunks) != 0 {
+		t.Error("Chunk of an empty map", len(chunks))
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Chunk accepted a size of 0")
+		}
+	}()
+	o.Chunk(0)
+}
+
+func TestOrderedMap_Page(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		o.Set(k, i)
+	}
+	cases := []struct {
+		offset, limit int
+		want          string
+	}{
+		{0, 2, "a0 b1"},
+		{2, 2, "c2 d3"},
+		{4, 2, "e4"},
+		{5, 2, ""},
+		{9, 2, ""},
+		{-3, 1, "a0"},
+		{1, 0, ""},
+		{1, -1, ""},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, p := range o.Page(c.offset, c.limit) {
+			got = append(got, fmt.Sprintf("%s%d", p.Key(), p.value))
+		}
+		if s := strings.Join(got, " "); s != c.want {
+			t.Errorf("Page(%d, %d) = %s, want %s", c.offset, c.limit, s, c.want)
+		}
+	}
+}
+
+func TestOrderedMap_HeadTail(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		o.Set(k, i)
+	}
+	keys := func(pairs []Pair[int]) string {
+		var ks []string
+		for _, p := range pairs {
+			ks = append(ks, p.key)
+		}
+		return strings.Join(ks, ",")
+	}
+	if head, tail := o.HeadTail(2); keys(head) != "a,b" || keys(tail) != "d,e" {
+		t.Error("HeadTail(2)", keys(head), keys(tail))
+	}
+	if head, tail := o.HeadTail(3); keys(head) != "a,b,c" || keys(tail) != "d,e" {
+		t.Error("HeadTail(3)", keys(head), keys(tail))
+	}
+	if head, tail := o.HeadTail(10); keys(head) != "a,b,c,d,e" || len(tail) != 0 {
+		t.Error("HeadTail(10)", keys(head), keys(tail))
+	}
+	if head, tail := o.HeadTail(0); len(head) != 0 || len(tail) != 0 {
+		t.Error("HeadTail(0)", keys(head), keys(tail))
+	}
+	if len(o.Keys()) != 5 {
+		t.Error("HeadTail modified the map")
+	}
+}
+
+func TestOrderedMap_KeysWhere(t *testing.T) {
+	o := New[string]()
+	o.Set("c", "x")
+	o.Set("a", "")
+	o.Set("b", "y")
+	keys := o.KeysWhere(func(value string) bool { return value != "" })
+	if len(keys) != 2 || keys[0] != "c" || keys[1] != "b" {
+		t.Error("KeysWhere result is incorrect", keys)
+	}
+	if keys = o.KeysWhere(func(value string) bool { return false }); len(keys) != 0 {
+		t.Error("KeysWhere without matches", keys)
+	}
+}
+
+func TestOrderedMap_ValuesMap(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 1)
+	o.Set("a", 2)
+	m := o.ValuesMap()
+	if len(m) != 2 || m["a"] != 2 || m["b"] != 1 {
+		t.Error("ValuesMap is incorrect", m)
+	}
+	c := o.ValuesMapCopy()
+	if len(c) != 2 || c["a"] != 2 || c["b"] != 1 {
+		t.Error("ValuesMapCopy is incorrect", c)
+	}
+	c["a"] = 3
+	delete(c, "b")
+	if v, _ := o.Get("a"); v != 2 || o.ValuesMap()["b"] != 1 {
+		t.Error("modifying ValuesMapCopy changed the map")
+	}
+}
+
+func TestOrderedMap_Find(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("c", 4)
+	calls := 0
+	k, v, ok := o.Find(func(key string, value int) bool {
+		calls++
+		return value%2 == 0
+	})
+	if !ok || k != "b" || v != 2 || calls != 2 {
+		t.Error("Find result is incorrect", k, v, ok, calls)
+	}
+	if k, v, ok = o.Find(func(string, int) bool { return false }); ok || k != "" || v != 0 {
+		t.Error("Find without matches", k, v, ok)
+	}
+}
+
+func TestOrderedMap_AllAny(t *testing.T) {
+	o := New[int]()
+	positive := func(key string, value int) bool { return value > 0 }
+	if !o.All(positive) || o.Any(positive) {
+		t.Error("All/Any on an empty map")
+	}
+	o.Set("a", 1)
+	o.Set("b", -1)
+	o.Set("c", 2)
+	if o.All(positive) {
+		t.Error("All with a negative value")
+	}
+	if !o.Any(positive) {
+		t.Error("Any with positive values")
+	}
+	calls := 0
+	o.All(func(key string, value int) bool {
+		calls++
+		return value > 0
+	})
+	if calls != 2 {
+		t.Error("All did not stop at the first failure", calls)
+	}
+}
+
+func TestOrderedMap_CountBy(t *testing.T) {
+	o := New[int]()
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		o.Set(k, i)
+	}
+	counts := o.CountBy(func(key string, v int) string {
+		if v%3 == 2 {
+			return "two"
+		}
+		return []string{"zero", "one"}[v%3]
+	})
+	b, _ := counts.MarshalJSON()
+	if string(b) != `{"zero":2,"one":2,"two":1}` {
+		t.Error("CountBy result is incorrect", string(b))
+	}
+	if len(New[int]().CountBy(func(string, int) string { return "" }).Keys()) != 0 {
+		t.Error("CountBy of an empty map")
+	}
+}
+
+func TestOrderedMap_CompressRuns(t *testing.T) {
+	o := New[string]()
+	for _, kv := range [][2]string{{"t1", "up"}, {"t2", "up"}, {"t3", "down"}, {"t4", "up"}, {"t5", "up"}, {"t6", "up"}} {
+		o.Set(kv[0], kv[1])
+	}
+	runs := o.CompressRuns(func(a, b string) bool { return a == b })
+	var got []string
+	for _, r := range runs {
+		got = append(got, fmt.Sprintf("%s:%s*%d", r.Key, r.Value, r.Count))
+	}
+	if s := strings.Join(got, " "); s != "t1:up*2 t3:down*1 t4:up*3" {
+		t.Error("CompressRuns result is incorrect", s)
+	}
+	if len(o.Keys()) != 6 {
+		t.Error("CompressRuns modified the map")
+	}
+	if runs = New[string]().CompressRuns(func(a, b string) bool { return true }); runs == nil || len(runs) != 0 {
+		t.Error("CompressRuns of an empty map", runs)
+	}
+}
+
+func TestOrderedMap_GetOrElse(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	calls := 0
+	fn := func() int {
+		calls++
+		return 42
+	}
+	if v := o.GetOrElse("a", fn); v != 1 || calls != 0 {
+		t.Error("GetOrElse on a hit", v, calls)
+	}
+	if v := o.GetOrElse("b", fn); v != 42 || calls != 1 {
+		t.Error("GetOrElse on a miss", v, calls)
+	}
+	if _, ok := o.Get("b"); ok {
+		t.Error("GetOrElse stored the value")
+	}
+	if v := o.GetOrElseSet("b", fn); v != 42 || calls != 2 {
+		t.Error("GetOrElseSet on a miss", v, calls)
+	}
+	if v := o.GetOrElseSet("b", fn); v != 42 || calls != 2 {
+		t.Error("GetOrElseSet on a hit", v, calls)
+	}
+	if k := o.Keys(); len(k) != 2 || k[1] != "b" {
+		t.Error("GetOrElseSet did not append the key", k)
+	}
+}
+
+func TestOrderedMap_GetAny(t *testing.T) {
+	o := New[string]()
+	o.Set("db_host", "a")
+	o.Set("host", "b")
+	if k, v, ok := o.GetAny("DB_HOST", "host", "db_host"); !ok || k != "host" || v != "b" {
+		t.Error("GetAny is incorrect", k, v, ok)
+	}
+	if k, v, ok := o.GetAny("port", "PORT"); ok || k != "" || v != "" {
+		t.Error("GetAny matched a missing key", k, v)
+	}
+	if _, _, ok := o.GetAny(); ok {
+		t.Error("GetAny matched without keys")
+	}
+}
+
+func TestOrderedMap_GetErr(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	if v, err := o.GetErr("a"); err != nil || v != 1 {
+		t.Error("GetErr on a hit", v, err)
+	}
+	if _, err := o.GetErr("b"); !errors.Is(err, ErrKeyNotFound) || !strings.Contains(err.Error(), `"b"`) {
+		t.Error("GetErr on a miss", err)
+	}
+	if v := o.MustGet("a"); v != 1 {
+		t.Error("MustGet on a hit", v)
+	}
+	defer func() {
+		if r, _ := recover().(error); !errors.Is(r, ErrKeyNotFound) {
+			t.Error("MustGet on a miss did not panic with ErrKeyNotFound", r)
+		}
+	}()
+	o.MustGet("b")
+}
+
+func TestOrderedMap_RequireKeys(t *testing.T) {
+	o := New[int]()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	if err := o.RequireKeys("b", "a"); err != nil {
+		t.Error("RequireKeys with present keys", err)
+	}
+	err := o.RequireKeys("z", "a", "y", "z")
+	if !errors.Is(err, ErrKeyNotFound) || err.Error() != `orderedmap: key not found: "z", "y"` {
+		t.Error("RequireKeys with missing keys", err)
+	}
+}
+
+func TestOrderedMap_SortKeysInvalid(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	bad := map[string]func(keys []string){
+		"replaced": func(keys []string) { keys[0] = "z" },
+		"repeated": func(keys []string) { keys[0] = keys[1] },
+	}
+	for name, sortFunc := range bad {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("SortKeys accepted a sort func that", name, "a key")
+				}
+			}()
+			o.SortKeys(sortFunc)
+		}()
+		if k := o.Keys(); len(k) != 2 || k[0] != "b" || k[1] != "a" {
+			t.Error("SortKeys changed the keys after a", name, "key", k)
+		}
+	}
+	// truncating the copy has no effect on the map
+	o.SortKeys(func(keys []string) { _ = keys[:0] })
+	if len(o.Keys()) != 2 {
+		t.Error("SortKeys lost keys", o.Keys())
+	}
+}
+
+func TestOrderedMap_Unzip(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	keys, values := o.Unzip()
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Error("Unzip keys", keys)
+	}
+	if len(values) != 2 || values[0] != 2 || values[1] != 1 {
+		t.Error("Unzip values", values)
+	}
+	keys[0] = "z"
+	if o.Keys()[0] != "b" {
+		t.Error("Unzip keys are not a copy")
+	}
+}
+
+func TestZip(t *testing.T) {
+	o, err := Zip([]string{"b", "a", "c", "a"}, []int{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal("Zip error", err)
+	}
+	b, _ := json.Marshal(o)
+	if string(b) != `{"b":1,"c":3,"a":4}` {
+		t.Error("Zip result is incorrect", string(b))
+	}
+	if _, err = Zip([]string{"a"}, []int{}); err == nil {
+		t.Error("Zip accepted slices of different lengths")
+	}
+}
+
+func TestDeepClone(t *testing.T) {
+	o := New[interface{}]()
+	o.SetEscapeHTML(false)
+	if err := o.UnmarshalJSON([]byte(`{"b":{"y":[1,{"z":2}],"x":3},"a":[4]}`)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	o.Set("m", map[string]interface{}{"k": []interface{}{5}})
+	o.Freeze()
+	c := DeepClone(o)
+	if !DeepEqual(o, c) {
+		t.Fatal("DeepClone is not equal to the original")
+	}
+	if c.IsFrozen() || c.escapeHTML != (htmlEscaping{skipKeys: true, skipValues: true}) {
+		t.Error("DeepClone settings", c.IsFrozen(), c.escapeHTML)
+	}
+	b := c.MustGet("b").(*OrderedMap[interface{}])
+	b.Set("w", 0)
+	b.MustGet("y").([]interface{})[1].(*OrderedMap[interface{}]).Set("z", 9)
+	c.MustGet("a").([]interface{})[0] = 9
+	c.MustGet("m").(map[string]interface{})["k"].([]interface{})[0] = 9
+	if s, _ := o.MarshalJSON(); string(s) != `{"b":{"y":[1,{"z":2}],"x":3},"a":[4],"m":{"k":[5]}}` {
+		t.Error("changing the clone changed the original", string(s))
+	}
+	if DeepClone(nil) != nil {
+		t.Error("DeepClone of nil")
+	}
+}
+
+func TestUnmarshalJSONByteOrderMark(t *testing.T) {
+	s := "\xef\xbb\xbf \n\t {\"b\":1,\"a\":2}"
+	o := New[int]()
+	if err := o.UnmarshalJSON([]byte(s)); err != nil {
+		t.Fatal("UnmarshalJSON error with a byte order mark", err)
+	}
+	if k := o.Keys(); len(k) != 2 || k[0] != "b" || k[1] != "a" {
+		t.Error("UnmarshalJSON keys with a byte order mark", k)
+	}
+	if err := o.Scan(s); err != nil {
+		t.Error("Scan error with a byte order mark", err)
+	}
+}
+
+func TestOrderedMap_ToDocument(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	doc := o.ToDocument()
+	if len(doc) != 2 || len(doc[0]) != 1 || doc[0]["b"] != 2 || len(doc[1]) != 1 || doc[1]["a"] != 1 {
+		t.Error("ToDocument result is incorrect", doc)
+	}
+}
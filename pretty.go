@@ -0,0 +1,205 @@
+package orderedmap
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PrettyOptions configures MarshalJSONPretty.
+type PrettyOptions struct {
+	// Indent is written once per nesting level, two spaces when empty.
+	Indent string
+	// Width is the line length, in bytes, up to which objects and arrays are
+	// kept on a single line. It defaults to 80.
+	Width int
+}
+
+// MarshalJSONPretty Like MarshalIndent, but objects and arrays fitting within
+// opts.Width on the current line are written on that line, as in
+// {"x": 1, "y": 2}, while longer ones are expanded one member per line.
+// Key order is preserved at every level.
+func (o OrderedMap[T]) MarshalJSONPretty(opts PrettyOptions) ([]byte, error) {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+	b, err := o.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	root, _, err := parsePrettyNode(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writePrettyNode(&buf, root, opts, 0, 0)
+	return buf.Bytes(), nil
+}
+
+// prettyNode is a JSON value split into its members. Scalars only have raw set.
+type prettyNode struct {
+	key      []byte
+	raw      []byte
+	open     byte
+	children []prettyNode
+	// inline is the length of the value written on a single line.
+	inline int
+}
+
+// closing returns the bracket ending an object or array node.
+func (n prettyNode) closing() byte {
+	if n.open == '[' {
+		return ']'
+	}
+	return '}'
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// scanString returns the end of the JSON string starting at data[i].
+func scanString(data []byte, i int) (int, error) {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("orderedmap: unterminated string at offset %d", i)
+}
+
+// parsePrettyNode parses the value starting at data[i] and returns it with the
+// offset following it. data is expected to be valid JSON.
+func parsePrettyNode(data []byte, i int) (prettyNode, int, error) {
+	i = skipSpace(data, i)
+	if i >= len(data) {
+		return prettyNode{}, 0, fmt.Errorf("orderedmap: unexpected end of JSON input")
+	}
+	var n prettyNode
+	switch c := data[i]; c {
+	case '{', '[':
+		n.open = c
+		i = skipSpace(data, i+1)
+		for i < len(data) && data[i] != n.closing() {
+			var key []byte
+			if c == '{' {
+				end, err := scanString(data, i)
+				if err != nil {
+					return n, 0, err
+				}
+				key = data[i:end]
+				i = skipSpace(data, end)
+				if i >= len(data) || data[i] != ':' {
+					return n, 0, fmt.Errorf("orderedmap: expected ':' at offset %d", i)
+				}
+				i++
+			}
+			child, end, err := parsePrettyNode(data, i)
+			if err != nil {
+				return n, 0, err
+			}
+			child.key = key
+			n.children = append(n.children, child)
+			if i = skipSpace(data, end); i < len(data) && data[i] == ',' {
+				i = skipSpace(data, i+1)
+			}
+		}
+		if i >= len(data) {
+			return n, 0, fmt.Errorf("orderedmap: unexpected end of JSON input")
+		}
+		// brackets plus ", " between members
+		n.inline = 2
+		for j, child := range n.children {
+			if j > 0 {
+				n.inline += 2
+			}
+			if child.key != nil {
+				n.inline += len(child.key) + 2
+			}
+			n.inline += child.inline
+		}
+		return n, i + 1, nil
+	case '"':
+		end, err := scanString(data, i)
+		if err != nil {
+			return n, 0, err
+		}
+		n.raw = data[i:end]
+		n.inline = len(n.raw)
+		return n, end, nil
+	}
+	end := i
+	for end < len(data) && !strings.ContainsRune(",]} \t\n\r", rune(data[end])) {
+		end++
+	}
+	n.raw = data[i:end]
+	n.inline = len(n.raw)
+	return n, end, nil
+}
+
+// writePrettyNode writes n at nesting level depth, col being the length of
+// the current line so far.
+func writePrettyNode(buf *bytes.Buffer, n prettyNode, opts PrettyOptions, depth, col int) {
+	if n.open == 0 {
+		buf.Write(n.raw)
+		return
+	}
+	buf.WriteByte(n.open)
+	if len(n.children) == 0 {
+		buf.WriteByte(n.closing())
+		return
+	}
+	// leave room for a trailing comma
+	if col+n.inline+1 <= opts.Width {
+		writePrettyInline(buf, n)
+		return
+	}
+	prefix := strings.Repeat(opts.Indent, depth+1)
+	for i, child := range n.children {
+		buf.WriteByte('\n')
+		buf.WriteString(prefix)
+		col := len(prefix)
+		if child.key != nil {
+			buf.Write(child.key)
+			buf.WriteString(": ")
+			col += len(child.key) + 2
+		}
+		writePrettyNode(buf, child, opts, depth+1, col)
+		if i < len(n.children)-1 {
+			buf.WriteByte(',')
+		}
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(strings.Repeat(opts.Indent, depth))
+	buf.WriteByte(n.closing())
+}
+
+// writePrettyInline writes the members of n and its closing bracket on a
+// single line.
+func writePrettyInline(buf *bytes.Buffer, n prettyNode) {
+	for i, child := range n.children {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if child.key != nil {
+			buf.Write(child.key)
+			buf.WriteString(": ")
+		}
+		if child.open == 0 {
+			buf.Write(child.raw)
+			continue
+		}
+		buf.WriteByte(child.open)
+		writePrettyInline(buf, child)
+	}
+	buf.WriteByte(n.closing())
+}
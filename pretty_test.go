@@ -0,0 +1,47 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalJSONPretty(t *testing.T) {
+	o := New[interface{}]()
+	o.Set("name", "x")
+	inner := New[int]()
+	inner.Set("y", 2)
+	inner.Set("x", 1)
+	o.Set("point", inner)
+	o.Set("tags", []string{"alpha", "beta", "gamma", "delta"})
+	o.Set("empty", []int{})
+	b, err := o.MarshalJSONPretty(PrettyOptions{Width: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "name": "x",
+  "point": {"y": 2, "x": 1},
+  "tags": [
+    "alpha",
+    "beta",
+    "gamma",
+    "delta"
+  ],
+  "empty": []
+}`
+	if string(b) != expected {
+		t.Error("MarshalJSONPretty is incorrect", string(b))
+	}
+	if !json.Valid(b) {
+		t.Error("MarshalJSONPretty output is not valid JSON")
+	}
+
+	b, err = o.MarshalJSONPretty(PrettyOptions{Indent: "\t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "{\n\t\"name\": \"x\",\n\t\"point\": {\"y\": 2, \"x\": 1},\n\t\"tags\": [\"alpha\", \"beta\", \"gamma\", \"delta\"],\n\t\"empty\": []\n}"
+	if string(b) != expected {
+		t.Error("MarshalJSONPretty default width is incorrect", string(b))
+	}
+}
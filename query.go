@@ -0,0 +1,95 @@
+package orderedmap
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FromURLValues Build a map from v with the keys of order first, in that
+// order, followed by the other keys of v in lexical order. Keys of order
+// missing from v are skipped. The value slices are shared with v.
+func FromURLValues(v url.Values, order []string) *OrderedMap[[]string] {
+	o := New[[]string]()
+	for _, k := range order {
+		if values, ok := v[k]; ok {
+			o.Set(k, values)
+		}
+	}
+	rest := make([]string, 0, len(v))
+	for k := range v {
+		if _, ok := o.values[k]; !ok {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		o.Set(k, v[k])
+	}
+	return o
+}
+
+// FromRawQuery Parse a URL query string as url.ParseQuery does, keeping the
+// keys in the order they first appear. Like url.ParseQuery, it returns the
+// first decoding error met along with the pairs that could be decoded.
+func FromRawQuery(query string) (*OrderedMap[[]string], error) {
+	o := New[[]string]()
+	var firstErr error
+	for query != "" {
+		var pair string
+		pair, query, _ = strings.Cut(query, "&")
+		if strings.Contains(pair, ";") {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("orderedmap: invalid semicolon separator in query")
+			}
+			continue
+		}
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(key)
+		if err == nil {
+			value, err = url.QueryUnescape(value)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		values, _ := o.Get(key)
+		o.Set(key, append(values, value))
+	}
+	return o, firstErr
+}
+
+// EncodeQuery Encode the map as a URL query string, in the order of the keys
+// rather than sorted as url.Values.Encode does. A []string value produces one
+// parameter per element, other values are formatted with fmt.Sprint.
+func (o *OrderedMap[T]) EncodeQuery() string {
+	var sb strings.Builder
+	write := func(key, value string) {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(url.QueryEscape(value))
+	}
+	for _, k := range o.keys {
+		key := url.QueryEscape(k)
+		switch value := interface{}(o.values[k]).(type) {
+		case string:
+			write(key, value)
+		case []string:
+			for _, v := range value {
+				write(key, v)
+			}
+		default:
+			write(key, fmt.Sprint(value))
+		}
+	}
+	return sb.String()
+}
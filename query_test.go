@@ -0,0 +1,70 @@
+package orderedmap
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFromURLValues(t *testing.T) {
+	v := url.Values{"c": {"3"}, "a": {"1", "2"}, "b": {"x"}, "d": nil}
+	o := FromURLValues(v, []string{"b", "missing", "c"})
+	if k := strings.Join(o.Keys(), " "); k != "b c a d" {
+		t.Error("FromURLValues keys", k)
+	}
+	if a, _ := o.Get("a"); len(a) != 2 || a[1] != "2" {
+		t.Error("FromURLValues values", a)
+	}
+}
+
+func TestFromRawQuery(t *testing.T) {
+	o, err := FromRawQuery("z=1&a=b%20c&z=2&&empty=&flag&sp+ace=%2B")
+	if err != nil {
+		t.Fatal("FromRawQuery error", err)
+	}
+	if k := strings.Join(o.Keys(), "|"); k != "z|a|empty|flag|sp ace" {
+		t.Error("FromRawQuery keys", k)
+	}
+	want := map[string]string{"z": "1,2", "a": "b c", "empty": "", "flag": "", "sp ace": "+"}
+	for k, v := range want {
+		if got, _ := o.Get(k); strings.Join(got, ",") != v {
+			t.Errorf("FromRawQuery %q = %q, want %q", k, got, v)
+		}
+	}
+
+	o, err = FromRawQuery("a=1&b=%zz&c=3;d")
+	if err == nil {
+		t.Error("FromRawQuery accepted an invalid escape")
+	}
+	if k := strings.Join(o.Keys(), " "); k != "a" {
+		t.Error("FromRawQuery keys after an error", k)
+	}
+}
+
+func TestOrderedMap_EncodeQuery(t *testing.T) {
+	o := New[string]()
+	o.Set("z", "1")
+	o.Set("a b", "x&y=+")
+	o.Set("m", "")
+	if q := o.EncodeQuery(); q != "z=1&a+b=x%26y%3D%2B&m=" {
+		t.Error("EncodeQuery result is incorrect", q)
+	}
+
+	multi, err := FromRawQuery("b=2&a=1&b=3&c=")
+	if err != nil {
+		t.Fatal("FromRawQuery error", err)
+	}
+	if q := multi.EncodeQuery(); q != "b=2&b=3&a=1&c=" {
+		t.Error("EncodeQuery with slices", q)
+	}
+
+	n := New[interface{}]()
+	n.Set("n", 1)
+	n.Set("s", "v")
+	if q := n.EncodeQuery(); q != "n=1&s=v" {
+		t.Error("EncodeQuery with other values", q)
+	}
+	if q := New[string]().EncodeQuery(); q != "" {
+		t.Error("EncodeQuery of an empty map", q)
+	}
+}
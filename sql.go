@@ -0,0 +1,31 @@
+package orderedmap
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Scan Implement sql.Scanner, reading the map from a JSON document such as a
+// json or jsonb column. Key order is preserved; a NULL column empties the map.
+func (o *OrderedMap[T]) Scan(src interface{}) error {
+	if o.frozen {
+		return ErrFrozen
+	}
+	switch data := src.(type) {
+	case []byte:
+		return o.UnmarshalJSON(data)
+	case string:
+		return o.UnmarshalJSON([]byte(data))
+	case nil:
+		o.keys = []string{}
+		o.values = map[string]T{}
+		return nil
+	}
+	return fmt.Errorf("orderedmap: cannot scan %T", src)
+}
+
+// Value Implement driver.Valuer, storing the map as compact ordered JSON.
+// Not to be confused with Pair.Value, which returns the value of one entry.
+func (o OrderedMap[T]) Value() (driver.Value, error) {
+	return o.MarshalJSON()
+}
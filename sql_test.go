@@ -0,0 +1,42 @@
+package orderedmap
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = &OrderedMap[int]{}
+	_ driver.Valuer = OrderedMap[int]{}
+)
+
+func TestOrderedMap_ScanValue(t *testing.T) {
+	o := New[interface{}]()
+	if err := o.Scan([]byte(`{"b":1, "a":{"d":2,"c":3}}`)); err != nil {
+		t.Fatal("Scan error", err)
+	}
+	v, err := o.Value()
+	if err != nil {
+		t.Fatal("Value error", err)
+	}
+	if string(v.([]byte)) != `{"b":1,"a":{"d":2,"c":3}}` {
+		t.Error("Value is incorrect", string(v.([]byte)))
+	}
+
+	if err = o.Scan(`{"z":true}`); err != nil {
+		t.Fatal("Scan string error", err)
+	}
+	if k := o.Keys(); len(k) != 1 || k[0] != "z" {
+		t.Error("Scan string keys", k)
+	}
+	if err = o.Scan(nil); err != nil {
+		t.Fatal("Scan nil error", err)
+	}
+	if len(o.Keys()) != 0 {
+		t.Error("Scan nil did not empty the map", o.Keys())
+	}
+	if err = o.Scan(42); err == nil {
+		t.Error("Scan accepted an int")
+	}
+}
@@ -0,0 +1,59 @@
+package orderedmap
+
+// Number is satisfied by the integer and floating point types.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Ordered is satisfied by the types supporting the < operator.
+type Ordered interface {
+	Number | ~string
+}
+
+// Sum Return the sum of the values, 0 for an empty map.
+func Sum[T Number](o *OrderedMap[T]) T {
+	var sum T
+	for _, k := range o.keys {
+		sum += o.values[k]
+	}
+	return sum
+}
+
+// Min Return the smallest value, the first one in order on ties. It returns
+// false for an empty map.
+func Min[T Ordered](o *OrderedMap[T]) (T, bool) {
+	var m T
+	for i, k := range o.keys {
+		if v := o.values[k]; i == 0 || v < m {
+			m = v
+		}
+	}
+	return m, len(o.keys) > 0
+}
+
+// Max Return the largest value, the first one in order on ties. It returns
+// false for an empty map.
+func Max[T Ordered](o *OrderedMap[T]) (T, bool) {
+	var m T
+	for i, k := range o.keys {
+		if v := o.values[k]; i == 0 || v > m {
+			m = v
+		}
+	}
+	return m, len(o.keys) > 0
+}
+
+// Mean Return the arithmetic mean of the values. It returns false for an
+// empty map.
+func Mean[T Number](o *OrderedMap[T]) (float64, bool) {
+	if len(o.keys) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, k := range o.keys {
+		sum += float64(o.values[k])
+	}
+	return sum / float64(len(o.keys)), true
+}
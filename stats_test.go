@@ -0,0 +1,50 @@
+package orderedmap
+
+import (
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	o := New[int]()
+	if _, ok := Min(o); ok {
+		t.Error("Min of an empty map")
+	}
+	if _, ok := Max(o); ok {
+		t.Error("Max of an empty map")
+	}
+	if _, ok := Mean(o); ok {
+		t.Error("Mean of an empty map")
+	}
+	if s := Sum(o); s != 0 {
+		t.Error("Sum of an empty map", s)
+	}
+	o.Set("a", 3)
+	o.Set("b", -2)
+	o.Set("c", 7)
+	o.Set("d", 2)
+	if s := Sum(o); s != 10 {
+		t.Error("Sum", s, "!= 10")
+	}
+	if m, ok := Min(o); !ok || m != -2 {
+		t.Error("Min", m, ok)
+	}
+	if m, ok := Max(o); !ok || m != 7 {
+		t.Error("Max", m, ok)
+	}
+	if m, ok := Mean(o); !ok || m != 2.5 {
+		t.Error("Mean", m, ok)
+	}
+
+	f := New[float64]()
+	f.Set("x", 0.5)
+	f.Set("y", 1.5)
+	if s := Sum(f); s != 2 {
+		t.Error("Sum of floats", s)
+	}
+	s := New[string]()
+	s.Set("x", "pear")
+	s.Set("y", "apple")
+	if m, ok := Min(s); !ok || m != "apple" {
+		t.Error("Min of strings", m, ok)
+	}
+}
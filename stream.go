@@ -0,0 +1,47 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// StreamDecode Read a JSON object from r and call fn for each of its entries,
+// in order, with the raw bytes of the value, without keeping the entries in
+// memory. Duplicate keys are passed to fn every time they occur. It stops at
+// the first error returned by fn and returns it. Data following the object is
+// not read.
+func StreamDecode(r io.Reader, fn func(key string, value json.RawMessage) error) error {
+	return streamDecode(json.NewDecoder(r), fn)
+}
+
+// streamDecode reads the object of StreamDecode from dec, leaving dec after
+// its closing brace.
+func streamDecode(dec *json.Decoder, fn func(key string, value json.RawMessage) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("orderedmap: cannot stream %v as an object", token)
+	}
+	for dec.More() {
+		token, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("orderedmap: unexpected token %v", token)
+		}
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return err
+		}
+		if err = fn(key, value); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token() // skip '}'
+	return err
+}
@@ -0,0 +1,38 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamDecode(t *testing.T) {
+	var got []string
+	err := StreamDecode(strings.NewReader(`{"b":1,"a":{"x":[1, 2]},"c":"s"}`), func(key string, value json.RawMessage) error {
+		got = append(got, key+"="+string(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatal("StreamDecode error", err)
+	}
+	if s := strings.Join(got, " "); s != `b=1 a={"x":[1, 2]} c="s"` {
+		t.Error("StreamDecode entries are incorrect", s)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = StreamDecode(strings.NewReader(`{"a":1,"b":2}`), func(string, json.RawMessage) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Error("StreamDecode did not stop on error", err, calls)
+	}
+
+	for _, s := range []string{`[1]`, `{"a":1`, ``} {
+		if err = StreamDecode(strings.NewReader(s), func(string, json.RawMessage) error { return nil }); err == nil {
+			t.Errorf("StreamDecode accepted %q", s)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package orderedmap
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// anyPairer is implemented by every OrderedMap instantiation.
+type anyPairer interface {
+	anyPairs() []*Pair[interface{}]
+}
+
+// anyPairs returns the entries in order with their values as interface{}.
+func (o OrderedMap[T]) anyPairs() []*Pair[interface{}] {
+	pairs := make([]*Pair[interface{}], len(o.keys))
+	for i, k := range o.keys {
+		pairs[i] = &Pair[interface{}]{k, o.values[k]}
+	}
+	return pairs
+}
+
+// TemplateFuncs Return template functions giving access to ordered maps of
+// any value type:
+//
+//	keys   func(m *OrderedMap[T]) ([]string, error)        the keys in order
+//	values func(m *OrderedMap[T]) ([]interface{}, error)   the values in order
+//	pairs  func(m *OrderedMap[T]) ([]*Pair[interface{}], error) the entries in
+//	       order, exposing .Key and .Value
+//
+// For html/template, convert the result with html/template.FuncMap.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"keys": func(m interface{}) ([]string, error) {
+			pairs, err := templatePairs(m)
+			if err != nil {
+				return nil, err
+			}
+			keys := make([]string, len(pairs))
+			for i, p := range pairs {
+				keys[i] = p.key
+			}
+			return keys, nil
+		},
+		"values": func(m interface{}) ([]interface{}, error) {
+			pairs, err := templatePairs(m)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]interface{}, len(pairs))
+			for i, p := range pairs {
+				values[i] = p.value
+			}
+			return values, nil
+		},
+		"pairs": templatePairs,
+	}
+}
+
+func templatePairs(m interface{}) ([]*Pair[interface{}], error) {
+	p, ok := m.(anyPairer)
+	if !ok {
+		return nil, fmt.Errorf("orderedmap: %T is not an ordered map", m)
+	}
+	return p.anyPairs(), nil
+}
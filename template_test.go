@@ -0,0 +1,26 @@
+package orderedmap
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	o := New[int]()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	o.Set("c", 3)
+	tmpl := template.Must(template.New("test").Funcs(TemplateFuncs()).Parse(
+		`{{range keys .}}{{.}};{{end}}|{{range values .}}{{.}};{{end}}|{{range pairs .}}{{.Key}}={{.Value}};{{end}}`))
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, o); err != nil {
+		t.Fatal("Template error", err)
+	}
+	if sb.String() != "b;a;c;|2;1;3;|b=2;a=1;c=3;" {
+		t.Error("Template output is incorrect", sb.String())
+	}
+	if err := tmpl.Execute(&sb, "not a map"); err == nil {
+		t.Error("Template accepted a value that is not an ordered map")
+	}
+}
@@ -0,0 +1,119 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Types Return a map from each key, in order, to the JSON type of its value:
+// "object", "array", "string", "number", "bool" or "null". Values
+// implementing json.Marshaler are classified by their JSON output.
+func (o *OrderedMap[T]) Types() *OrderedMap[string] {
+	types := New[string]()
+	types.escapeHTML = o.escapeHTML
+	for _, k := range o.keys {
+		types.keys = append(types.keys, k)
+		types.values[k] = jsonType(o.values[k])
+	}
+	return types
+}
+
+// jsonType returns the JSON type v is marshaled as.
+func jsonType(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case jsonEncoder:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "null"
+		}
+		return "object"
+	case json.Number:
+		return "number"
+	case json.RawMessage:
+		return rawJSONType(value)
+	case json.Marshaler:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "null"
+		}
+		if b, err := value.MarshalJSON(); err == nil {
+			return rawJSONType(b)
+		}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		if rv.IsNil() {
+			return "null"
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as a base64 string
+			return "string"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map:
+		if rv.IsNil() {
+			return "null"
+		}
+		return "object"
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return "null"
+		}
+		return jsonType(rv.Elem().Interface())
+	}
+	return "object"
+}
+
+// rawJSONType returns the type of the JSON value b.
+func rawJSONType(b []byte) string {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	if len(b) == 0 {
+		return "null"
+	}
+	switch b[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// IsEmptyValue Report whether v is nil, a nil pointer, or an empty string,
+// slice, array, map or ordered map. Numbers and booleans are never empty.
+func IsEmptyValue(v interface{}) bool {
+	if m, ok := v.(interface{ IsEmpty() bool }); ok {
+		if rv := reflect.ValueOf(m); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return true
+		}
+		return m.IsEmpty()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOrderedMap_Types(t *testing.T) {
+	o := New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(`{"o":{},"a":[],"s":"","n":1,"b":false,"z":null}`)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	var nilMap *OrderedMap[interface{}]
+	o.Set("int", 3)
+	o.Set("number", json.Number("1.5"))
+	o.Set("raw", json.RawMessage(` [1]`))
+	o.Set("time", time.Now())
+	o.Set("bytes", []byte("x"))
+	o.Set("map", map[string]int{})
+	o.Set("nilMap", nilMap)
+	o.Set("struct", struct{}{})
+	b, err := o.Types().MarshalJSON()
+	if err != nil {
+		t.Fatal("MarshalJSON error", err)
+	}
+	want := `{"o":"object","a":"array","s":"string","n":"number","b":"bool","z":"null",` +
+		`"int":"number","number":"number","raw":"array","time":"string","bytes":"string",` +
+		`"map":"object","nilMap":"null","struct":"object"}`
+	if string(b) != want {
+		t.Error("Types is incorrect", string(b))
+	}
+}
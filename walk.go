@@ -0,0 +1,324 @@
+package orderedmap
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WalkLeaves Call fn, depth-first and in order, for every value that is not a
+// nested *OrderedMap[interface{}] or []interface{}, with the keys and slice
+// indexes leading to it. The walk stops when fn returns false. The path slice
+// is reused between calls; copy it to keep it.
+func (o *OrderedMap[T]) WalkLeaves(fn func(path []string, value interface{}) bool) {
+	path := make([]string, 0, 8)
+	for _, k := range o.keys {
+		if !walkLeaves(append(path, k), o.values[k], fn) {
+			return
+		}
+	}
+}
+
+func walkLeaves(path []string, v interface{}, fn func(path []string, value interface{}) bool) bool {
+	switch value := v.(type) {
+	case *OrderedMap[interface{}]:
+		if value == nil {
+			break
+		}
+		for _, k := range value.keys {
+			if !walkLeaves(append(path, k), value.values[k], fn) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		for i, e := range value {
+			if !walkLeaves(append(path, strconv.Itoa(i)), e, fn) {
+				return false
+			}
+		}
+		return true
+	}
+	return fn(path, v)
+}
+
+// MaxWalkDepth is the deepest nesting Walk descends into before giving up,
+// which also stops it on maps containing themselves.
+const MaxWalkDepth = 1000
+
+// Visitor receives the structure of a map from Walk. Paths hold the keys and
+// slice indexes leading to the value, the root object having an empty path;
+// they are reused between calls. Returning an error stops the walk.
+type Visitor interface {
+	EnterObject(path []string) error
+	LeaveObject(path []string) error
+	EnterArray(path []string) error
+	LeaveArray(path []string) error
+	Scalar(path []string, value interface{}) error
+}
+
+// Walk Traverse the map depth-first and in order, calling v when entering and
+// leaving the map itself, nested *OrderedMap[interface{}] and []interface{}
+// values, and for every other value. Walk returns the first error of v, or an
+// error when the nesting exceeds MaxWalkDepth.
+func (o *OrderedMap[T]) Walk(v Visitor) error {
+	path := make([]string, 0, 8)
+	if err := v.EnterObject(path); err != nil {
+		return err
+	}
+	for _, k := range o.keys {
+		if err := walkValue(append(path, k), o.values[k], v); err != nil {
+			return err
+		}
+	}
+	return v.LeaveObject(path)
+}
+
+func walkValue(path []string, value interface{}, v Visitor) error {
+	if len(path) > MaxWalkDepth {
+		return fmt.Errorf("orderedmap: walk exceeds depth %d at %s", MaxWalkDepth, strings.Join(path[:8], ".")+"...")
+	}
+	switch value := value.(type) {
+	case *OrderedMap[interface{}]:
+		if value == nil {
+			break
+		}
+		if err := v.EnterObject(path); err != nil {
+			return err
+		}
+		for _, k := range value.keys {
+			if err := walkValue(append(path, k), value.values[k], v); err != nil {
+				return err
+			}
+		}
+		return v.LeaveObject(path)
+	case []interface{}:
+		if value == nil {
+			break
+		}
+		if err := v.EnterArray(path); err != nil {
+			return err
+		}
+		for i, e := range value {
+			if err := walkValue(append(path, strconv.Itoa(i)), e, v); err != nil {
+				return err
+			}
+		}
+		return v.LeaveArray(path)
+	}
+	return v.Scalar(path, value)
+}
+
+// MapStrings Replace, in place, every string value by fn applied to it,
+// including the strings held by nested *OrderedMap[interface{}],
+// []interface{} and map[string]interface{} values. Keys and other values are
+// left unchanged. A container held several times, or holding itself, is
+// updated once.
+func (o *OrderedMap[T]) MapStrings(fn func(string) string) {
+	o.mustBeMutable()
+	visited := map[copyKey]bool{}
+	if self, ok := any(o).(*OrderedMap[interface{}]); ok {
+		visited[copyKey{reflect.ValueOf(self).Pointer(), -1}] = true
+	}
+	o.mapStrings(fn, visited)
+}
+
+func (o *OrderedMap[T]) mapStrings(fn func(string) string, visited map[copyKey]bool) {
+	for _, k := range o.keys {
+		if v, ok := mapStrings(o.values[k], fn, visited).(T); ok {
+			o.values[k] = v
+		}
+	}
+}
+
+// mapStrings returns v with its strings replaced by fn, updating containers in
+// place. visited holds the containers already updated, as keyed by deepCopy.
+func mapStrings(v interface{}, fn func(string) string, visited map[copyKey]bool) interface{} {
+	switch value := v.(type) {
+	case string:
+		return fn(value)
+	case *OrderedMap[interface{}]:
+		key := copyKey{reflect.ValueOf(value).Pointer(), -1}
+		if value != nil && !visited[key] {
+			visited[key] = true
+			value.mustBeMutable()
+			value.mapStrings(fn, visited)
+		}
+	case []interface{}:
+		key := copyKey{reflect.ValueOf(value).Pointer(), len(value)}
+		if len(value) > 0 && !visited[key] {
+			visited[key] = true
+			for i, e := range value {
+				value[i] = mapStrings(e, fn, visited)
+			}
+		}
+	case map[string]interface{}:
+		key := copyKey{reflect.ValueOf(value).Pointer(), -2}
+		if value != nil && !visited[key] {
+			visited[key] = true
+			for k, e := range value {
+				value[k] = mapStrings(e, fn, visited)
+			}
+		}
+	}
+	return v
+}
+
+// Tree Render the map as an indented outline for debugging, one entry per
+// line in order: nested *OrderedMap[interface{}] and []interface{} values are
+// listed below their key, indented by two spaces, and strings are quoted. A
+// map containing itself is marked <cycle> instead of being listed again.
+func (o *OrderedMap[T]) Tree() string {
+	var sb strings.Builder
+	visiting := map[uintptr]bool{}
+	if o.values != nil {
+		visiting[reflect.ValueOf(o.values).Pointer()] = true
+	}
+	for _, k := range o.keys {
+		writeTree(&sb, 0, k, o.values[k], visiting)
+	}
+	return sb.String()
+}
+
+func writeTree(sb *strings.Builder, depth int, label string, v interface{}, visiting map[uintptr]bool) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(label)
+	switch value := v.(type) {
+	case *OrderedMap[interface{}]:
+		if value == nil {
+			break
+		}
+		if len(value.keys) == 0 {
+			sb.WriteString(": {}\n")
+			return
+		}
+		id := reflect.ValueOf(value.values).Pointer()
+		if visiting[id] {
+			sb.WriteString(": <cycle>\n")
+			return
+		}
+		visiting[id] = true
+		sb.WriteByte('\n')
+		for _, k := range value.keys {
+			writeTree(sb, depth+1, k, value.values[k], visiting)
+		}
+		delete(visiting, id)
+		return
+	case []interface{}:
+		if value == nil {
+			break
+		}
+		if len(value) == 0 {
+			sb.WriteString(": []\n")
+			return
+		}
+		sb.WriteByte('\n')
+		for i, e := range value {
+			writeTree(sb, depth+1, "["+strconv.Itoa(i)+"]", e, visiting)
+		}
+		return
+	case string:
+		sb.WriteString(": ")
+		sb.WriteString(strconv.Quote(value))
+		sb.WriteByte('\n')
+		return
+	}
+	sb.WriteString(": ")
+	if v == nil {
+		sb.WriteString("null")
+	} else {
+		fmt.Fprint(sb, v)
+	}
+	sb.WriteByte('\n')
+}
+
+// Prune Delete the given keys from the map and, recursively, from the
+// *OrderedMap[interface{}] values it holds, including those inside
+// []interface{} values. The other keys keep their order. A map held several
+// times, or holding itself, is pruned once.
+func (o *OrderedMap[T]) Prune(removeKeys ...string) {
+	o.DeleteMulti(removeKeys...)
+	o.eachDescendantMap(func(m *OrderedMap[interface{}]) { m.DeleteMulti(removeKeys...) })
+}
+
+// Project Keep only the given keys in the map and, recursively, in the
+// *OrderedMap[interface{}] values it holds, including those inside
+// []interface{} values. A nested map is only kept when its own key is listed.
+// A map held several times, or holding itself, is projected once.
+func (o *OrderedMap[T]) Project(keepKeys ...string) {
+	o.Retain(keepKeys...)
+	o.eachDescendantMap(func(m *OrderedMap[interface{}]) { m.Retain(keepKeys...) })
+}
+
+// Contains Report whether sub is held, as the same instance, by a value of the
+// map or of a nested *OrderedMap[interface{}], including those inside
+// []interface{} values. The map itself only counts when it holds itself.
+// Maps containing themselves are visited once.
+func (o *OrderedMap[T]) Contains(sub *OrderedMap[interface{}]) bool {
+	if sub == nil {
+		return false
+	}
+	visited := map[*OrderedMap[interface{}]]bool{}
+	var contains func(m *OrderedMap[interface{}]) bool
+	contains = func(m *OrderedMap[interface{}]) bool {
+		if m == sub {
+			return true
+		}
+		if visited[m] {
+			return false
+		}
+		visited[m] = true
+		found := false
+		m.eachNestedMap(func(n *OrderedMap[interface{}]) {
+			found = found || contains(n)
+		})
+		return found
+	}
+	found := false
+	o.eachNestedMap(func(n *OrderedMap[interface{}]) {
+		found = found || contains(n)
+	})
+	return found
+}
+
+// eachDescendantMap calls fn for the ordered maps held by o at any depth,
+// directly or inside slices, a map before those it holds. Each map is visited
+// once, so maps holding themselves do not recurse forever.
+func (o *OrderedMap[T]) eachDescendantMap(fn func(m *OrderedMap[interface{}])) {
+	visited := map[*OrderedMap[interface{}]]bool{}
+	if self, ok := any(o).(*OrderedMap[interface{}]); ok {
+		visited[self] = true
+	}
+	var visit func(m *OrderedMap[interface{}])
+	visit = func(m *OrderedMap[interface{}]) {
+		if visited[m] {
+			return
+		}
+		visited[m] = true
+		fn(m)
+		m.eachNestedMap(visit)
+	}
+	o.eachNestedMap(visit)
+}
+
+// eachNestedMap calls fn for the ordered maps held by the values of o,
+// directly or inside slices, without going deeper.
+func (o *OrderedMap[T]) eachNestedMap(fn func(m *OrderedMap[interface{}])) {
+	var visit func(v interface{})
+	visit = func(v interface{}) {
+		switch value := v.(type) {
+		case *OrderedMap[interface{}]:
+			if value != nil {
+				fn(value)
+			}
+		case []interface{}:
+			for _, e := range value {
+				visit(e)
+			}
+		}
+	}
+	for _, k := range o.keys {
+		visit(o.values[k])
+	}
+}
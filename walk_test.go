@@ -0,0 +1,210 @@
+package orderedmap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOrderedMap_WalkLeaves(t *testing.T) {
+	o := New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(`{"b":1,"a":{"y":[true,{"z":null}],"x":"s"},"e":{},"c":[]}`)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	var visited []string
+	o.WalkLeaves(func(path []string, value interface{}) bool {
+		visited = append(visited, strings.Join(path, "."))
+		return true
+	})
+	if got := strings.Join(visited, " "); got != "b a.y.0 a.y.1.z a.x" {
+		t.Error("WalkLeaves order is incorrect", got)
+	}
+
+	visited = nil
+	o.WalkLeaves(func(path []string, value interface{}) bool {
+		visited = append(visited, strings.Join(path, "."))
+		return len(visited) < 2
+	})
+	if len(visited) != 2 {
+		t.Error("WalkLeaves did not stop", visited)
+	}
+}
+
+// recorder is a Visitor logging its calls.
+type recorder struct {
+	calls []string
+	stop  string
+}
+
+func (r *recorder) record(event string, path []string) error {
+	r.calls = append(r.calls, event+"("+strings.Join(path, ".")+")")
+	if event == r.stop {
+		return errors.New("stop")
+	}
+	return nil
+}
+
+func (r *recorder) EnterObject(path []string) error { return r.record("{", path) }
+func (r *recorder) LeaveObject(path []string) error { return r.record("}", path) }
+func (r *recorder) EnterArray(path []string) error  { return r.record("[", path) }
+func (r *recorder) LeaveArray(path []string) error  { return r.record("]", path) }
+func (r *recorder) Scalar(path []string, value interface{}) error {
+	return r.record(fmt.Sprint(value), path)
+}
+
+func TestOrderedMap_Walk(t *testing.T) {
+	o := New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(`{"b":1,"a":{"y":[true]}}`)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	r := &recorder{}
+	if err := o.Walk(r); err != nil {
+		t.Fatal("Walk error", err)
+	}
+	want := "{() 1(b) {(a) [(a.y) true(a.y.0) ](a.y) }(a) }()"
+	if got := strings.Join(r.calls, " "); got != want {
+		t.Errorf("Walk calls = %s, want %s", got, want)
+	}
+
+	r = &recorder{stop: "["}
+	if err := o.Walk(r); err == nil || len(r.calls) != 4 {
+		t.Error("Walk did not stop on error", err, r.calls)
+	}
+
+	o.Set("self", o)
+	if err := o.Walk(&recorder{}); err == nil {
+		t.Error("Walk accepted a cycle")
+	}
+}
+
+func TestOrderedMap_MapStrings(t *testing.T) {
+	o := New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(`{" k ":" a ","n":{"l":[" b ",1,{"c":" c "}]},"x":true}`)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	o.Set("m", map[string]interface{}{"d": " d "})
+	o.MapStrings(strings.TrimSpace)
+	b, err := o.MarshalJSON()
+	if err != nil {
+		t.Fatal("MarshalJSON error", err)
+	}
+	if string(b) != `{" k ":"a","n":{"l":["b",1,{"c":"c"}]},"x":true,"m":{"d":"d"}}` {
+		t.Error("MapStrings result is incorrect", string(b))
+	}
+
+	s := New[string]()
+	s.Set("a", "x")
+	s.MapStrings(strings.ToUpper)
+	if v, _ := s.Get("a"); v != "X" {
+		t.Error("MapStrings on a string map", v)
+	}
+}
+
+func TestOrderedMap_MapStringsCycle(t *testing.T) {
+	o := New[interface{}]()
+	inner := New[interface{}]()
+	inner.Set("s", "a")
+	inner.Set("self", inner)
+	list := []interface{}{"b", inner}
+	o.Set("self", o)
+	o.Set("inner", inner)
+	o.Set("list", list)
+	o.Set("again", list)
+	o.MapStrings(func(s string) string { return s + "!" })
+	if v := inner.MustGet("s"); v != "a!" {
+		t.Error("MapStrings with cycles", v)
+	}
+	if list[0] != "b!" {
+		t.Error("MapStrings updated a shared slice more than once", list[0])
+	}
+}
+
+func TestOrderedMap_Tree(t *testing.T) {
+	o := New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(`{"b":1,"a":{"y":[true,{"z":null}],"x":"s"},"e":{},"c":[]}`)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	o.Set("self", o)
+	want := `b: 1
+a
+  y
+    [0]: true
+    [1]
+      z: null
+  x: "s"
+e: {}
+c: []
+self: <cycle>
+`
+	if got := o.Tree(); got != want {
+		t.Errorf("Tree =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestOrderedMap_PruneProject(t *testing.T) {
+	doc := `{"id":1,"password":"x","user":{"name":"n","password":"y","tokens":[{"id":2,"secret":"z"}]},"secret":"s"}`
+	o := New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(doc)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	o.Prune("password", "secret")
+	if b, _ := o.MarshalJSON(); string(b) != `{"id":1,"user":{"name":"n","tokens":[{"id":2}]}}` {
+		t.Error("Prune result is incorrect", string(b))
+	}
+
+	o = New[interface{}]()
+	if err := o.UnmarshalJSON([]byte(doc)); err != nil {
+		t.Fatal("UnmarshalJSON error", err)
+	}
+	o.Project("user", "tokens", "id")
+	if b, _ := o.MarshalJSON(); string(b) != `{"id":1,"user":{"tokens":[{"id":2}]}}` {
+		t.Error("Project result is incorrect", string(b))
+	}
+}
+
+func TestOrderedMap_PruneProjectCycle(t *testing.T) {
+	o := New[interface{}]()
+	inner := New[interface{}]()
+	inner.Set("secret", 1)
+	inner.Set("id", 2)
+	inner.Set("self", inner)
+	o.Set("self", o)
+	o.Set("inner", inner)
+	o.Set("list", []interface{}{inner})
+	o.Set("secret", 3)
+	o.Prune("secret")
+	if k := strings.Join(inner.Keys(), ","); k != "id,self" {
+		t.Error("Prune with cycles", k)
+	}
+	if k := strings.Join(o.Keys(), ","); k != "self,inner,list" {
+		t.Error("Prune with cycles", k)
+	}
+	o.Project("inner", "self")
+	if k := strings.Join(inner.Keys(), ","); k != "self" {
+		t.Error("Project with cycles", k)
+	}
+	if k := strings.Join(o.Keys(), ","); k != "self,inner" {
+		t.Error("Project with cycles", k)
+	}
+}
+
+func TestOrderedMap_Contains(t *testing.T) {
+	shared := New[interface{}]()
+	shared.Set("x", 1)
+	inner := New[interface{}]()
+	inner.Set("list", []interface{}{"a", shared})
+	o := New[interface{}]()
+	o.Set("inner", inner)
+	if !o.Contains(shared) || !o.Contains(inner) {
+		t.Error("Contains missed a nested map")
+	}
+	if o.Contains(New[interface{}]()) || o.Contains(nil) || o.Contains(o) {
+		t.Error("Contains found a map not held")
+	}
+	inner.Set("self", inner)
+	o.Set("loop", o)
+	if !o.Contains(o) || o.Contains(New[interface{}]()) {
+		t.Error("Contains with cycles is incorrect")
+	}
+}